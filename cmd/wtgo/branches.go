@@ -0,0 +1,28 @@
+package main
+
+import (
+	"github.com/sokinpui/wt-go/internal/worktree"
+	"github.com/spf13/cobra"
+)
+
+var branchesCmd = &cobra.Command{
+	Use:   "branches",
+	Short: "List local branches by most recent commit, marking those with worktrees",
+	Long: `branches lists local branches sorted by committer date, most recent first.
+Branches that already have a worktree are marked with "+", like 'git branch'.
+
+Usage:
+  wtgo branches | fzf | wtgo    Create or switch to a recently used branch
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := worktree.ListBranchesByRecency(); err != nil {
+			worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+			exit(exitCodeOf(err))
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(branchesCmd)
+}
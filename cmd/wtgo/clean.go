@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/sokinpui/wt-go/internal/log"
+	"github.com/sokinpui/wt-go/internal/worktree"
+	"github.com/spf13/cobra"
+)
+
+var cleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Remove worktrees whose branches' upstream branches were deleted",
+	Long: `clean runs 'git fetch --all --prune', then finds worktrees whose branches
+track an upstream branch that is gone, as after a pull request was merged and
+its branch deleted, and offers to remove those worktrees and branches.
+Main branches, the current worktree and pinned or locked worktrees are kept.
+The branches are deleted even if their commits are not merged anywhere, as
+after a squash merge; the confirmation is the check.
+
+To create a worktree for a branch named "clean", run 'wtgo -- clean'.
+
+Usage:
+  wtgo clean            Remove the worktrees after confirmation
+  wtgo clean --dry-run  Print the worktrees that would be removed
+  wtgo clean --yes      Remove them without asking
+  wtgo clean --force    Also remove worktrees with uncommitted changes
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := worktree.FetchAll(true); err != nil {
+			worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+			exit(exitCodeOf(err))
+		}
+
+		gone, err := worktree.FindGoneWorktrees()
+		if err != nil {
+			worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+			exit(exitCodeOf(err))
+		}
+
+		if len(gone) == 0 {
+			log.Infof("No worktrees with deleted upstream branches found.")
+			return
+		}
+
+		if dryRunFlag {
+			printRemovalPlan(gone)
+			return
+		}
+
+		log.Infof("Worktrees whose upstream branches are gone:")
+		for _, wt := range gone {
+			fmt.Printf("%s (%s)\n", wt.Branch, wt.Path)
+		}
+		if !worktree.Confirm(fmt.Sprintf("Remove these %d worktrees and their branches?", len(gone))) {
+			log.Infof("Nothing removed.")
+			return
+		}
+
+		removeAll(gone)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cleanCmd)
+}
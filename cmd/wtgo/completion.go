@@ -0,0 +1,38 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/sokinpui/wt-go/internal/worktree"
+	"github.com/spf13/cobra"
+)
+
+// Shells may ask for completions more than once per invocation, e.g. when
+// the candidates are filtered again; list the branches only once.
+var (
+	localBranchNames    = sync.OnceValues(worktree.LocalBranchNames)
+	worktreeBranchNames = sync.OnceValues(worktree.WorktreeBranchNames)
+)
+
+// completeBranches completes the <branch> argument of the root command:
+// branches with a worktree for --rm, and all local branches otherwise.
+func completeBranches(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) != 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	list := localBranchNames
+	if removeFlag {
+		list = worktreeBranchNames
+	}
+	names, err := list()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+func init() {
+	// Cobra provides the `completion [bash|zsh|fish|powershell]` command itself.
+	rootCmd.ValidArgsFunction = completeBranches
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sokinpui/wt-go/internal/worktree"
+	"github.com/spf13/cobra"
+)
+
+var envFormat string
+
+var envCmd = &cobra.Command{
+	Use:   "env",
+	Short: "Print shell statements exporting wtgo-derived variables",
+	Long: `env prints statements that export WTGO_REPO_ROOT, WTGO_BRANCH,
+WTGO_WORKTREE_PATH and WTGO_COLLECTION_DIR for the current worktree.
+
+Usage:
+  eval "$(wtgo env)"                 POSIX shells (bash, zsh)
+  wtgo env --format=fish | source    fish
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		var format func(name, value string) string
+		switch envFormat {
+		case "sh":
+			format = func(name, value string) string {
+				return fmt.Sprintf("export %s=%s", name, quotePosix(value))
+			}
+		case "fish":
+			format = func(name, value string) string {
+				return fmt.Sprintf("set -gx %s %s", name, quoteFish(value))
+			}
+		default:
+			worktree.ReportError(worktree.CodeUsage, nil, "Error: Unknown format '%s'. Use 'sh' or 'fish'.\n", envFormat)
+			exit(exitUsage)
+		}
+
+		vars, err := worktree.EnvVars()
+		if err != nil {
+			worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+			exit(exitCodeOf(err))
+		}
+		for _, v := range vars {
+			fmt.Println(format(v.Name, v.Value))
+		}
+	},
+}
+
+// quotePosix single-quotes s for POSIX shells, where the only character that
+// needs escaping inside single quotes is the single quote itself.
+func quotePosix(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// quoteFish single-quotes s for fish, which supports \' and \\ inside single quotes.
+func quoteFish(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+}
+
+func init() {
+	envCmd.Flags().StringVar(&envFormat, "format", "sh", "Output format: sh or fish")
+	rootCmd.AddCommand(envCmd)
+}
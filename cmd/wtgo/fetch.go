@@ -0,0 +1,33 @@
+package main
+
+import (
+	"github.com/sokinpui/wt-go/internal/worktree"
+	"github.com/spf13/cobra"
+)
+
+var fetchPrune bool
+
+var fetchAllCmd = &cobra.Command{
+	Use:   "fetch-all",
+	Short: "Fetch all remotes once, reporting the remote-tracking refs that changed",
+	Long: `fetch-all runs 'git fetch --all' in the repository and reports which
+remote-tracking refs were created, updated or deleted. Run it before creating
+worktrees for several remote branches, or pass --fetch-first when creating.
+
+Usage:
+  wtgo fetch-all            Fetch all remotes
+  wtgo fetch-all --prune    Also delete refs whose remote branch is gone
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := worktree.FetchAll(fetchPrune); err != nil {
+			worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+			exit(exitCodeOf(err))
+		}
+	},
+}
+
+func init() {
+	fetchAllCmd.Flags().BoolVar(&fetchPrune, "prune", false, "Remove remote-tracking refs that no longer exist on the remote")
+	rootCmd.AddCommand(fetchAllCmd)
+}
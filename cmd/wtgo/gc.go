@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/sokinpui/wt-go/internal/log"
+	"github.com/sokinpui/wt-go/internal/worktree"
+	"github.com/spf13/cobra"
+)
+
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Find directories in the worktree collection that git no longer tracks",
+	Long: `gc compares the contents of the wtgo-managed <repo>.wt collection directory
+against git's worktree list and reports directories git doesn't know about.
+
+Usage:
+  wtgo gc            Report orphaned directories
+  wtgo gc --force    Delete orphaned directories after confirmation
+  wtgo gc --dry-run  Print the directories that would be deleted
+  wtgo gc --force --yes  Delete orphaned directories without asking
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		dirs, err := worktree.FindOrphanedDirs()
+		if err != nil {
+			worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+			exit(exitCodeOf(err))
+		}
+
+		if len(dirs) == 0 {
+			log.Infof("No orphaned directories found.")
+			return
+		}
+
+		if dryRunFlag {
+			for _, dir := range dirs {
+				fmt.Printf("would delete directory: %s\n", dir)
+			}
+			return
+		}
+
+		log.Infof("Orphaned directories:")
+		for _, dir := range dirs {
+			fmt.Println(dir)
+		}
+
+		if !forceFlag {
+			log.Infof("Run 'wtgo gc --force' to delete them.")
+			return
+		}
+
+		if !worktree.Confirm(fmt.Sprintf("Delete %d orphaned directories?", len(dirs))) {
+			log.Infof("Aborted.")
+			exit(exitError)
+		}
+		if err := worktree.RemoveOrphanedDirs(dirs); err != nil {
+			exit(exitError)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(gcCmd)
+}
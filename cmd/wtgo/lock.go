@@ -0,0 +1,46 @@
+package main
+
+import (
+	"github.com/sokinpui/wt-go/internal/worktree"
+	"github.com/spf13/cobra"
+)
+
+var lockReason string
+
+var lockCmd = &cobra.Command{
+	Use:   "lock <branch> [reason]",
+	Short: "Lock the worktree of <branch> with git so it cannot be removed or pruned",
+	Args:  cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		reason := lockReason
+		if len(args) == 2 {
+			if reason != "" {
+				worktree.ReportError(worktree.CodeUsage, nil, "Error: Give the lock reason either as an argument or with --reason, not both.\n")
+				exit(exitUsage)
+			}
+			reason = args[1]
+		}
+		if err := worktree.LockWorktree(args[0], reason); err != nil {
+			worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+			exit(exitCodeOf(err))
+		}
+	},
+}
+
+var unlockCmd = &cobra.Command{
+	Use:   "unlock <branch>",
+	Short: "Unlock the worktree of <branch>",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := worktree.UnlockWorktree(args[0]); err != nil {
+			worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+			exit(exitCodeOf(err))
+		}
+	},
+}
+
+func init() {
+	lockCmd.Flags().StringVar(&lockReason, "reason", "", "Why the worktree is locked, shown by 'wtgo --locked'")
+	rootCmd.AddCommand(lockCmd)
+	rootCmd.AddCommand(unlockCmd)
+}
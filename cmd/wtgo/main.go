@@ -2,10 +2,18 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/sokinpui/wt-go/internal/git"
+	"github.com/sokinpui/wt-go/internal/log"
 	"github.com/sokinpui/wt-go/internal/worktree"
 	"github.com/spf13/cobra"
 )
@@ -17,38 +25,425 @@ var rootCmd = &cobra.Command{
 
 Usage:
   wtgo                            List all Git worktrees
+  wtgo --strict [--json]          List worktrees, exiting 1 if there are none
+  wtgo --locked                   List only worktrees locked by git, with their lock reasons
+  wtgo --all                      List worktrees including branches matched by .wtgoignore
+                                  (glob patterns, one per line, in the git common dir, e.g. .git/.wtgoignore)
+  wtgo --track                    List worktrees with each branch's [ahead N, behind M] against its upstream
+  wtgo --age                      List worktrees oldest last commit first, with how long ago each was committed
+  wtgo -p|--list-paths            List worktrees as <branch><TAB><path> lines, e.g. for fzf | cut -f2
+  wtgo --pull [--rebase] [<branch>] Fast-forward <branch>'s worktree (default: the current one) from its upstream
+  wtgo --current                  Print the current worktree's branch (@<short commit> when detached)
+  wtgo --path <branch> [--json]   Print the worktree path of <branch> if it has one; exits 0 either way
+  wtgo --status [--json]          Show each worktree's branch, dirtiness and ahead/behind counts vs. upstream
+  wtgo --all-branches             List all local branches, marking those with a worktree
   wtgo <branch>                   Create a new worktree and branch named <branch>
+                                  (tracking origin/<branch> if it exists, unless --no-track)
+  wtgo -- <branch>                Create <branch> even if it is named like a subcommand, e.g. clean or top
+  wtgo --pr <n>                   Fetch pull request <n> from origin into branch pr-<n> and create its worktree
+                                  (WTGO_PR_REMOTE and WTGO_PR_REF, e.g. merge-requests/{n}/head, override)
+  wtgo --from <ref> <branch>      Create <branch> starting at <ref> instead of the current HEAD
+  wtgo --base-dir <dir> <branch>  Create the worktree of <branch> in <dir> instead of the usual location
+  wtgo --fetch <branch>           Fetch origin/<branch> first, so a new <branch> tracks its latest commit
+  wtgo -i|--interactive           Pick a worktree from a numbered menu and print its path
   wtgo -                          Switch to the previous worktree
+  wtgo -<n>                       Switch to the worktree <n> steps back (up to 20 are remembered)
+  wtgo top                        Switch to the main worktree
   wtgo --rm [-f|--force] <branch> Remove worktree <branch> and delete branch <branch> (use with caution)
+                                  (refuses the default branch and WTGO_PROTECTED_BRANCHES, default main,master)
+  wtgo --rm .                     Remove the current worktree and its branch, printing the main worktree's path
+  wtgo --rm <branch>...           Remove several worktrees and branches, going on past failures
+  wtgo --rm --keep-branch <branch> Remove worktree <branch> but keep branch <branch>
+  wtgo --rename <old> <new>       Rename branch <old> to <new>, moving its worktree along
+  wtgo --move <branch> <path>     Move the worktree of <branch> to <path>
+  wtgo undo-rm                    Restore the branch and worktree deleted by the last --rm
+  wtgo --rm --stale [-f]          Prune worktrees whose directories are gone, offering to delete their branches
+                                  (--dry-run prints the plan, --yes skips the confirmation)
+  wtgo --rm --merged [<base>]     Remove worktrees and branches merged into <base> (default: the default branch)
+                                  (skips protected branches, the current, pinned and locked worktrees; --dry-run, --yes)
+  wtgo --rm --all-merged-into <ref> Remove worktrees whose branches are merged into <ref>, after deselecting
+                                  any to keep from a numbered menu (--yes removes all; --dry-run)
+  wtgo clean [--dry-run]          Fetch with pruning, then remove worktrees and branches whose upstream is gone
+  wtgo --prune                    Drop records of worktrees whose directories are gone, listing them
+  wtgo --detach <commit-ish>      Create a worktree detached at <commit-ish>, named detached-<commit-ish>
+  wtgo --rm --detach <path>       Remove a detached worktree by path (or by the <commit-ish> it was created for)
+  wtgo --orphan <branch>          Create a worktree with a new orphan branch <branch> (no history)
+  wtgo --recurse-submodules <branch> Create <branch> with its submodules initialized (default with WTGO_RECURSE_SUBMODULES=1)
+  wtgo --name-template <t> <branch> Name the new directory after <t>, e.g. {repo}-{branch} or {branch}-{date}
+                                  (also WTGO_NAME_TEMPLATE or wtgo.nameTemplate; placeholders {branch}, {repo}, {date}, {user})
+  wtgo --no-switch <branch>       Create <branch> and print its path without recording it for 'wtgo -'
+  wtgo --dry-run <branch>         Print the git worktree add command that would run, changing nothing
+  wtgo --stash <branch>           Stash current changes, then create/switch to <branch>
+  wtgo --force-fresh [-y] <branch> Delete <branch> and its worktree, then recreate it
+                                  (from --from, default the default branch, without tracking origin/<branch>)
+  wtgo --copy-from <src> <branch> Create <branch> and copy the uncommitted changes of <src>'s worktree
+  wtgo --copy <glob> <branch>     Create <branch> with files matching <glob> (e.g. .env) copied from the current worktree (repeatable)
+  wtgo --tmux <branch>            Create/switch to <branch> and open it in a new tmux window
+  wtgo --open <branch>            Create/switch to <branch> and open it in $WTGO_EDITOR (or $EDITOR, $VISUAL)
+  wtgo -C|--repo <path> ...       Work on the repository at <path>, as if started there (like git -C)
+                                  (path arguments and --base-dir, --cd-file, --relative stay relative to here)
+  wtgo -q|--quiet ...             Only report warnings and errors (-v|--verbose adds debugging details)
+  wtgo --timeout <duration> ...   Fail with exit code 124 if wtgo is still running git after <duration>
+                                  (without it, only fetches and other network commands time out, after 60s each)
+  wtgo --cd-file <file> <branch>  Also write the resulting worktree path to <file>
+  wtgo --relative <branch>        Print the worktree path relative to the current directory
+  wtgo --print0 [<branch>]        Terminate printed paths and listed branch names with NUL
+  wtgo --issue <id> <name>        Create <name> on a branch named after issue <id> (see wtgo.branchTemplate)
+  wtgo pin|unpin <branch>         Protect (or unprotect) a worktree from bulk cleanup
+  wtgo lock <branch> [reason]     Lock a worktree with git so it cannot be removed (wtgo unlock <branch> undoes it)
+  wtgo where [-v] <branch>        Print the worktree path of <branch>, or exit 1 if it has none
+  wtgo branches                   List local branches by recency, marking those with worktrees
+  wtgo open-pr [--no-browse]      Push the current branch and open its pull request page
+  wtgo env [--format=fish]        Print export statements for the current worktree context
+  wtgo sync [<branch>|--all]      Pull upstream changes into worktrees (--rebase, --autostash)
+  wtgo fetch-all [--prune]        Fetch all remotes once (or pass --fetch-first when creating)
+  wtgo gc [--force]               Report (or delete) orphaned directories in the collection directory
+  wtgo completion bash|zsh|fish   Print a shell completion script (completes branch names)
   git branch | fzf | wtgo         Create a new worktree for a branch selected via fzf
+                                  (the last non-empty line of stdin is used)
+
+New worktrees go to <repo>.wt next to the repository. Set WTGO_WORKTREE_DIR
+or WTGO_GLOBAL_ROOT (or git config wtgo.globalRoot), e.g. to ~/worktrees, to
+create them under <root>/<repo>-<hash> instead, or set wtgo.layout=xdg to use
+the user cache directory.
+Slashes in branch names become '_' in directory names; set WTGO_SANITIZE=nested
+(or wtgo.sanitize) to keep them as subdirectories, so feat/x and feat_x differ.
+
+Paths are printed to stdout without a trailing newline; listings print one
+branch per line. Messages go to stderr.
+
+The exit status is 0 on success, 2 for invalid flags or arguments, 3 outside
+a git repository, 124 when a timeout expires, and 1 for any other failure.
 `,
+	// Arbitrary args keep `wtgo <branch>` working alongside subcommands.
+	Args: cobra.ArbitraryArgs,
+	// Execute reports rejected flags and arguments itself, honouring --json.
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		worktree.JSONErrors = jsonFlag
+		worktree.JSONOutput = jsonFlag
+		worktree.ListPaths = listPathsFlag
+		worktree.ListTracking = trackFlag
+		worktree.ListAge = ageFlag
+		worktree.ListIgnored = allFlag
+		worktree.AssumeYes = yesFlag
+		worktree.Output.CdFile = cdFileFlag
+		worktree.Output.Print0 = print0Flag
+		worktree.Output.Relative = relativeFlag
+		worktree.NameTemplate = nameTemplateFlag
+		if quietFlag && verboseFlag {
+			worktree.ReportError(worktree.CodeUsage, nil, "Error: The --quiet and --verbose flags cannot be combined.\n")
+			exit(exitUsage)
+		}
+		if quietFlag {
+			log.SetLevel(log.LevelWarn)
+		}
+		if verboseFlag {
+			log.SetLevel(log.LevelDebug)
+		}
+		if timeoutFlag < 0 {
+			worktree.ReportError(worktree.CodeUsage, nil, "Error: The --timeout flag must not be negative.\n")
+			exit(exitUsage)
+		}
+		if !cmd.Flags().Changed("timeout") {
+			git.SetNetworkTimeout(defaultNetworkTimeout)
+		} else if timeoutFlag > 0 {
+			// One deadline for the whole operation, prompts and hooks
+			// included, so a CI step cannot hang however many commands run.
+			ctx, cancel := context.WithTimeout(context.Background(), timeoutFlag)
+			cancelOperation = cancel
+			git.SetContext(ctx)
+		}
+		if repoFlag != "" {
+			// Paths given on the command line stay relative to where wtgo
+			// was started, not to the repository it changes into.
+			if wd, err := os.Getwd(); err == nil {
+				startDir = wd
+			}
+			worktree.Output.CdFile = fromStartDir(cdFileFlag)
+			worktree.Output.RelativeTo = startDir
+			baseDirFlag = fromStartDir(baseDirFlag)
+			if _, err := worktree.EnterRepo(repoFlag); err != nil {
+				worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+				exit(exitCodeOf(err))
+			}
+		}
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		if forceFlag && !removeFlag {
-			fmt.Fprintf(os.Stderr, "Error: The --force/-f flag can only be used with --rm.\n")
-			os.Exit(1)
+			worktree.ReportError(worktree.CodeUsage, nil, "Error: The --force/-f flag can only be used with --rm.\n")
+			exit(exitUsage)
+		}
+
+		if issueFlag != "" && removeFlag {
+			worktree.ReportError(worktree.CodeUsage, nil, "Error: The --issue flag cannot be combined with --rm.\n")
+			exit(exitUsage)
+		}
+
+		// Only the plain create and the removals know how to describe
+		// themselves without acting.
+		if dryRunFlag && !removeFlag && (orphanFlag || detachFlag || prFlag != "") {
+			worktree.ReportError(worktree.CodeUsage, nil, "Error: The --dry-run flag cannot be combined with --orphan, --detach or --pr.\n")
+			exit(exitUsage)
+		}
+
+		if orphanFlag {
+			if removeFlag || len(args) != 1 {
+				worktree.ReportError(worktree.CodeUsage, nil, "Error: The --orphan flag requires exactly one argument (the branch name) and cannot be combined with --rm.\n")
+				exit(exitUsage)
+			}
+			branchName := resolveBranchName(args[0])
+			if err := worktree.CreateOrphanWorktree(branchName); err != nil {
+				worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+				exit(exitCodeOf(err))
+			}
+			recordIssue(branchName)
+			return
+		}
+
+		if pathFlag != "" {
+			if removeFlag || len(args) != 0 {
+				worktree.ReportError(worktree.CodeUsage, nil, "Error: The --path flag takes no arguments besides the branch name and cannot be combined with --rm.\n")
+				exit(exitUsage)
+			}
+			if err := worktree.PrintWorktreeLookup(pathFlag); err != nil {
+				worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+				exit(exitCodeOf(err))
+			}
+			return
+		}
+
+		if prFlag != "" {
+			if removeFlag || len(args) != 0 {
+				worktree.ReportError(worktree.CodeUsage, nil, "Error: The --pr flag takes no arguments besides the pull request number and cannot be combined with --rm.\n")
+				exit(exitUsage)
+			}
+			branchName, err := worktree.FetchPullRequest(prFlag)
+			if err != nil {
+				worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+				exit(exitCodeOf(err))
+			}
+			createAndSwitch(branchName)
+			return
+		}
+
+		if renameFlag {
+			if removeFlag || len(args) != 2 {
+				worktree.ReportError(worktree.CodeUsage, nil, "Error: The --rename flag requires exactly two arguments (the old and new branch names) and cannot be combined with --rm.\n")
+				exit(exitUsage)
+			}
+			path, err := worktree.RenameWorktree(args[0], args[1])
+			if err != nil {
+				worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+				exit(exitCodeOf(err))
+			}
+			worktree.PrintPath(path)
+			return
+		}
+
+		if moveFlag {
+			if removeFlag || len(args) != 2 {
+				worktree.ReportError(worktree.CodeUsage, nil, "Error: The --move flag requires exactly two arguments (the branch name and the new path) and cannot be combined with --rm.\n")
+				exit(exitUsage)
+			}
+			path, err := worktree.MoveWorktree(args[0], fromStartDir(args[1]))
+			if err != nil {
+				worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+				exit(exitCodeOf(err))
+			}
+			worktree.PrintPath(path)
+			return
+		}
+
+		if detachFlag {
+			if len(args) != 1 || keepBranchFlag || staleFlag {
+				worktree.ReportError(worktree.CodeUsage, nil, "Error: The --detach flag requires exactly one argument (a commit-ish, or with --rm a worktree path).\n")
+				exit(exitUsage)
+			}
+			if removeFlag {
+				if dryRunFlag {
+					worktree.ReportError(worktree.CodeUsage, nil, "Error: The --dry-run flag cannot be combined with --rm --detach.\n")
+					exit(exitUsage)
+				}
+				// The argument is a path if one exists, else a commit-ish.
+				target := args[0]
+				if _, err := os.Stat(fromStartDir(target)); err == nil {
+					target = fromStartDir(target)
+				}
+				if err := worktree.RemoveDetachedWorktree(target, forceFlag); err != nil {
+					worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+					exit(exitCodeOf(err))
+				}
+				return
+			}
+			if err := worktree.CreateDetachedWorktree(args[0]); err != nil {
+				worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+				exit(exitCodeOf(err))
+			}
+			return
+		}
+
+		if interactiveFlag {
+			if len(args) != 0 || removeFlag {
+				worktree.ReportError(worktree.CodeUsage, nil, "Error: The --interactive flag takes no arguments and cannot be combined with --rm.\n")
+				exit(exitUsage)
+			}
+			path, err := worktree.PickWorktree()
+			if err != nil {
+				worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+				exit(exitCodeOf(err))
+			}
+			worktree.PrintPath(path)
+			return
+		}
+
+		if rebaseFlag && !pullFlag {
+			worktree.ReportError(worktree.CodeUsage, nil, "Error: The --rebase flag can only be used with --pull.\n")
+			exit(exitUsage)
+		}
+
+		if pullFlag {
+			if removeFlag || len(args) > 1 {
+				worktree.ReportError(worktree.CodeUsage, nil, "Error: The --pull flag takes at most one argument (the branch name) and cannot be combined with --rm.\n")
+				exit(exitUsage)
+			}
+			syncWorktree(args, worktree.SyncOptions{Rebase: rebaseFlag})
+			return
+		}
+
+		if currentFlag {
+			if len(args) != 0 {
+				worktree.ReportError(worktree.CodeUsage, nil, "Error: The --current flag takes no arguments.\n")
+				exit(exitUsage)
+			}
+			name, err := worktree.CurrentName()
+			if err != nil {
+				worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+				exit(exitCodeOf(err))
+			}
+			fmt.Println(name)
+			return
+		}
+
+		if statusFlag {
+			if len(args) != 0 {
+				worktree.ReportError(worktree.CodeUsage, nil, "Error: The --status flag takes no arguments.\n")
+				exit(exitUsage)
+			}
+			if err := worktree.PrintWorktreeStatus(); err != nil {
+				worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+				exit(exitCodeOf(err))
+			}
+			return
+		}
+
+		if pruneFlag {
+			if removeFlag || len(args) != 0 {
+				worktree.ReportError(worktree.CodeUsage, nil, "Error: The --prune flag takes no arguments and cannot be combined with --rm.\n")
+				exit(exitUsage)
+			}
+			removed, err := worktree.PruneWorktrees()
+			if err != nil {
+				worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+				exit(exitCodeOf(err))
+			}
+			log.Infof("Pruned %d stale worktree entries.", len(removed))
+			return
+		}
+
+		if keepBranchFlag && (!removeFlag || staleFlag) {
+			worktree.ReportError(worktree.CodeUsage, nil, "Error: The --keep-branch flag can only be used with --rm <branch>.\n")
+			exit(exitUsage)
+		}
+
+		if mergedFlag && (!removeFlag || staleFlag) {
+			worktree.ReportError(worktree.CodeUsage, nil, "Error: The --merged flag can only be used with --rm.\n")
+			exit(exitUsage)
+		}
+
+		if allMergedIntoFlag != "" {
+			if !removeFlag || mergedFlag || staleFlag || keepBranchFlag || len(args) != 0 {
+				worktree.ReportError(worktree.CodeUsage, nil, "Error: The --all-merged-into flag must be used with --rm, takes no arguments besides the ref, and cannot be combined with --merged, --stale or --keep-branch.\n")
+				exit(exitUsage)
+			}
+			removeWorktreesMergedInto(allMergedIntoFlag)
+			return
+		}
+
+		if removeFlag && mergedFlag {
+			if len(args) > 1 || keepBranchFlag {
+				worktree.ReportError(worktree.CodeUsage, nil, "Error: The --rm --merged flags take at most one argument (the base branch) and cannot be combined with --keep-branch.\n")
+				exit(exitUsage)
+			}
+			var base string
+			if len(args) == 1 {
+				base = args[0]
+			}
+			removeMergedWorktrees(base)
+			return
+		}
+
+		if staleFlag && !removeFlag {
+			worktree.ReportError(worktree.CodeUsage, nil, "Error: The --stale flag can only be used with --rm.\n")
+			exit(exitUsage)
+		}
+
+		if removeFlag && staleFlag {
+			if len(args) != 0 {
+				worktree.ReportError(worktree.CodeUsage, nil, "Error: The --rm --stale flags take no arguments.\n")
+				exit(exitUsage)
+			}
+			removeStaleWorktrees()
+			return
 		}
 
 		if removeFlag { // Guard clause for --rm flag
-			if len(args) != 1 {
-				fmt.Fprintf(os.Stderr, "Error: The --rm flag requires exactly one argument (the branch name).\n")
-				os.Exit(1)
+			if len(args) == 0 {
+				worktree.ReportError(worktree.CodeUsage, nil, "Error: The --rm flag requires at least one argument (the branch name).\n")
+				exit(exitUsage)
+			}
+			if dryRunFlag {
+				planRemovals(args)
+				return
+			}
+			if len(args) == 1 && args[0] == "." {
+				path, err := worktree.RemoveCurrentWorktree(forceFlag, keepBranchFlag)
+				if err != nil {
+					worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+					exit(exitCodeOf(err))
+				}
+				worktree.PrintPath(path)
+				return
 			}
-			worktree.RemoveWorktreeAndBranch(args[0], forceFlag)
+			removeWorktrees(args)
 			return
 		}
 
+		if dryRunFlag && (stashFlag || forceFreshFlag || fetchFirstFlag || copyFromFlag != "") {
+			worktree.ReportError(worktree.CodeUsage, nil, "Error: The --dry-run flag cannot be combined with --stash, --force-fresh, --fetch-first or --copy-from.\n")
+			exit(exitUsage)
+		}
+
 		// If arguments are provided, process them directly.
 		if len(args) == 1 {
-			if args[0] == "-" {
-				path, err := worktree.SwitchToPreviousWorktree()
+			if steps, ok := historySteps(args[0]); ok {
+				if issueFlag != "" {
+					worktree.ReportError(worktree.CodeUsage, nil, "Error: The --issue flag cannot be used when switching to the previous worktree.\n")
+					exit(exitUsage)
+				}
+				path, err := worktree.SwitchToPreviousWorktree(steps)
 				if err != nil {
-					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-					os.Exit(1)
+					worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+					exit(exitCodeOf(err))
 				}
-				fmt.Print(path)
+				worktree.PrintPath(path)
 				return
 			}
-			worktree.CreateWorktreeAndBranch(args[0])
+			branchName := resolveBranchName(args[0])
+			fetchFirst()
+			discardForFresh(branchName)
+			createAndSwitch(branchName)
 			return
 		}
 
@@ -57,40 +452,479 @@ Usage:
 			stat, err := os.Stdin.Stat()
 			// Check if stdin is piped (not a character device like a terminal)
 			if err == nil && (stat.Mode()&os.ModeCharDevice) == 0 {
-				scanner := bufio.NewScanner(os.Stdin)
-				if scanner.Scan() {
-					branchName := strings.TrimSpace(scanner.Text())
-					if branchName != "" {
-						worktree.CreateWorktreeAndBranch(branchName)
-						return
-					}
+				branchName, err := lastNonEmptyLine(os.Stdin)
+				if err != nil {
+					worktree.ReportError(worktree.CodeError, err, "Error reading from stdin: %v\n", err)
+					exit(exitError)
 				}
-				if err := scanner.Err(); err != nil {
-					fmt.Fprintf(os.Stderr, "Error reading from stdin: %v\n", err)
-					os.Exit(1)
+				branchName = cleanPipedBranchName(branchName)
+				if branchName != "" {
+					branchName = resolveBranchName(branchName)
+					fetchFirst()
+					discardForFresh(branchName)
+					createAndSwitch(branchName)
+					return
 				}
 				// If stdin was piped but provided no valid branch name, fall through to list worktrees.
 			}
 			// No arguments and no valid stdin input, list worktrees.
-			worktree.ListWorktrees()
+			if allBranchesFlag {
+				if err := worktree.ListAllBranches(); err != nil {
+					worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+					exit(exitCodeOf(err))
+				}
+				return
+			}
+			listed, err := worktree.ListWorktrees(lockedFlag)
+			if err != nil {
+				worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+				exit(exitCodeOf(err))
+			}
+			if listed == 0 && strictFlag {
+				exit(exitError)
+			}
 			return
 		}
 
 		// If more than one argument is provided (and not --rm), it's an error.
-		fmt.Fprintf(os.Stderr, "Error: Too many arguments. See 'wtgo --help'.\n")
-		os.Exit(1)
+		worktree.ReportError(worktree.CodeUsage, nil, "Error: Too many arguments. See 'wtgo --help'.\n")
+		exit(exitUsage)
 	},
 }
 
 // removeFlag is a persistent flag to indicate removal of a worktree.
 var removeFlag bool
 var forceFlag bool
+var cdFileFlag string
+var orphanFlag bool
+var tmuxFlag bool
+var openFlag bool
+var stashFlag bool
+var jsonFlag bool
+var issueFlag string
+var timeoutFlag time.Duration
+var quietFlag bool
+var fetchFirstFlag bool
+var fetchFlag bool
+var prFlag string
+
+var pathFlag string
+
+var nameTemplateFlag string
+var baseDirFlag string
+var allBranchesFlag bool
+var copyFromFlag string
+var staleFlag bool
+var pruneFlag bool
+var moveFlag bool
+var renameFlag bool
+var interactiveFlag bool
+var relativeFlag bool
+var detachFlag bool
+var statusFlag bool
+
+var currentFlag bool
+var copyFlag []string
+var mergedFlag bool
+
+var allMergedIntoFlag string
+var keepBranchFlag bool
+var listPathsFlag bool
+var trackFlag bool
+
+var repoFlag string
+
+// startDir is the directory wtgo was started in, set when -C changes away
+// from it.
+var startDir string
+
+var ageFlag bool
+var allFlag bool
+var pullFlag bool
+var rebaseFlag bool
+var strictFlag bool
+var dryRunFlag bool
+var yesFlag bool
+var lockedFlag bool
+var forceFreshFlag bool
+
+var recurseSubmodulesFlag bool
+
+var noSwitchFlag bool
+var print0Flag bool
+var fromFlag string
+var noTrackFlag bool
+var verboseFlag bool
+
+// cancelOperation releases the deadline --timeout set, if any.
+var cancelOperation = func() {}
+
+// defaultNetworkTimeout bounds each git command talking to a remote unless
+// --timeout is given, so a git waiting on e.g. a credential prompt cannot
+// hang wtgo forever, while a long checkout still runs to completion.
+const defaultNetworkTimeout = 60 * time.Second
+
+// Exit statuses other than 0 for success, for scripts to tell failures apart.
+const (
+	exitError    = 1 // Any failure without a more specific status.
+	exitUsage    = 2 // Invalid flags or arguments.
+	exitNotARepo = 3 // Not run inside a git repository.
+	// exitTimeout is the exit status when a git invocation was killed
+	// because --timeout expired, matching timeout(1).
+	exitTimeout = 124
+)
+
+// exitCodeOf returns the exit status for a failure with err.
+func exitCodeOf(err error) int {
+	if worktree.ErrorCodeOf(err) == worktree.CodeNotARepo {
+		return exitNotARepo
+	}
+	return exitError
+}
+
+// exit ends the program with code, or with exitTimeout if the failure was
+// caused by a git invocation running out of time.
+func exit(code int) {
+	if code != 0 && git.TimedOut() {
+		code = exitTimeout
+	}
+	cancelOperation()
+	os.Exit(code)
+}
+
+// lastNonEmptyLine returns the last line of r that is not blank, with
+// surrounding whitespace (including the \r of CRLF line endings) trimmed.
+// Taking the last line lets tools print a header before the branch name.
+// It returns an empty string if r holds no such line.
+func lastNonEmptyLine(r io.Reader) (string, error) {
+	var last string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			last = line
+		}
+	}
+	return last, scanner.Err()
+}
+
+// fromStartDir resolves a relative path given on the command line against
+// the directory wtgo was started in, which -C leaves.
+func fromStartDir(path string) string {
+	if startDir == "" || path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(startDir, path)
+}
+
+// cleanPipedBranchName strips the decorations a branch name picks up on its
+// way through a pipeline: the `* ` and `+ ` markers `git branch` puts in front
+// of the current branch and branches checked out in other worktrees, the `✓ `
+// marker of `wtgo --all-branches`, and
+// surrounding single or double quotes added by fzf or shell quoting.
+func cleanPipedBranchName(name string) string {
+	name = strings.TrimSpace(strings.ReplaceAll(name, "\r", ""))
+	if len(name) >= 2 {
+		first, last := name[0], name[len(name)-1]
+		if first == last && (first == '"' || first == '\'') {
+			name = strings.TrimSpace(name[1 : len(name)-1])
+		}
+	}
+	for _, marker := range []string{"* ", "+ ", "✓ "} {
+		if rest, ok := strings.CutPrefix(name, marker); ok {
+			return strings.TrimSpace(rest)
+		}
+	}
+	return name
+}
+
+// resolveBranchName returns the branch to use for name, applying the branch
+// template when --issue is given. Invalid names end the program.
+func resolveBranchName(name string) string {
+	if issueFlag == "" {
+		return name
+	}
+	branchName, err := worktree.BranchNameForIssue(name, issueFlag)
+	if err != nil {
+		worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+		exit(exitCodeOf(err))
+	}
+	return branchName
+}
+
+// createAndSwitch creates or switches to the worktree of branchName, however
+// the name was given, and then applies the flags acting on the new worktree.
+// With --stash, the current changes are stashed first, once the target is
+// settled, and put back if the switch fails.
+func createAndSwitch(branchName string) {
+	var stash string
+	if stashFlag {
+		var err error
+		if stash, err = worktree.StashCurrentChanges(); err != nil {
+			worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+			exit(exitCodeOf(err))
+		}
+	}
+	if err := worktree.CreateWorktreeAndBranch(branchName, createOptions()); err != nil {
+		worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+		if stash != "" {
+			if err := worktree.PopStash(stash); err != nil {
+				log.Warnf("%v", err)
+			}
+		}
+		exit(exitCodeOf(err))
+	}
+	if dryRunFlag {
+		return
+	}
+	recordIssue(branchName)
+	copyChanges(branchName)
+	if tmuxFlag {
+		openTmuxWindow(branchName)
+	}
+	if openFlag {
+		openInEditor(branchName)
+	}
+}
+
+// fetchFirst fetches all remotes before a create when --fetch-first is given.
+// A failed fetch ends the program rather than creating from stale refs.
+func fetchFirst() {
+	if !fetchFirstFlag {
+		return
+	}
+	if err := worktree.FetchAll(false); err != nil {
+		worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+		exit(exitCodeOf(err))
+	}
+}
+
+// discardForFresh deletes branchName and its worktree before a create when
+// --force-fresh is given, so the branch is recreated from the base ref.
+func discardForFresh(branchName string) {
+	if !forceFreshFlag {
+		return
+	}
+	if err := worktree.DiscardBranch(branchName); err != nil {
+		worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+		exit(exitCodeOf(err))
+	}
+}
+
+// copyChanges seeds the worktree of branchName with the uncommitted changes
+// of the --copy-from branch, if one was given.
+func copyChanges(branchName string) {
+	if copyFromFlag == "" {
+		return
+	}
+
+	path, err := worktree.FindWorktreePathForBranch(branchName)
+	if err != nil || path == "" {
+		// Creation failed and has already been reported.
+		return
+	}
+
+	if err := worktree.CopyChanges(copyFromFlag, branchName); err != nil {
+		worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+		exit(exitCodeOf(err))
+	}
+}
+
+// removeWorktrees implements `wtgo --rm <branch>...`: it removes the
+// worktree and branch of each of branches, going on past failures, and exits
+// non-zero if any removal failed. With several branches, a result line is
+// logged for each.
+func removeWorktrees(branches []string) {
+	var firstErr error
+	for _, branch := range branches {
+		err := worktree.RemoveWorktreeAndBranch(branch, forceFlag, keepBranchFlag)
+		if err != nil {
+			worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		if len(branches) == 1 {
+			continue
+		}
+		if err != nil {
+			log.Infof("remove fail: %s", branch)
+		} else {
+			log.Infof("remove ok: %s", branch)
+		}
+	}
+	if firstErr != nil {
+		exit(exitCodeOf(firstErr))
+	}
+}
+
+// planRemovals implements `wtgo --rm --dry-run <branch>...`: it prints what
+// removing each branch would do, changing nothing, and exits with the code
+// of the first removal that would fail.
+func planRemovals(branches []string) {
+	var firstErr error
+	for _, branch := range branches {
+		if branch == "." {
+			current, err := worktree.CurrentRemovableBranch()
+			if err != nil {
+				worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+				exit(exitCodeOf(err))
+			}
+			branch = current
+		}
+		plan, err := worktree.RemovalPlan(branch, keepBranchFlag)
+		if err != nil {
+			worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		fmt.Printf("would %s\n", plan)
+	}
+	if firstErr != nil {
+		exit(exitCodeOf(firstErr))
+	}
+}
+
+// createOptions returns the worktree creation options given on the command line.
+func createOptions() worktree.CreateOptions {
+	return worktree.CreateOptions{
+		BaseRef:           fromFlag,
+		NoTrack:           noTrackFlag,
+		CopyPatterns:      copyFlag,
+		BaseDir:           baseDirFlag,
+		Fetch:             fetchFlag,
+		DryRun:            dryRunFlag,
+		RecurseSubmodules: recurseSubmodulesFlag,
+		NoSwitch:          noSwitchFlag,
+		Fresh:             forceFreshFlag,
+	}
+}
+
+// recordIssue associates branchName with the --issue id, if one was given.
+// Nothing is recorded when the worktree could not be created.
+func recordIssue(branchName string) {
+	if issueFlag == "" {
+		return
+	}
+
+	path, err := worktree.FindWorktreePathForBranch(branchName)
+	if err != nil || path == "" {
+		// Creation failed and has already been reported.
+		return
+	}
+
+	if err := worktree.RecordIssue(branchName, issueFlag); err != nil {
+		log.Warnf("could not record issue '%s' for branch '%s': %v", issueFlag, branchName, err)
+	}
+}
+
+// openTmuxWindow opens a tmux window for the worktree of branchName.
+// Outside tmux, the already printed path is all the user gets.
+func openTmuxWindow(branchName string) {
+	if !worktree.InTmux() {
+		log.Infof("Not inside tmux; skipping window creation.")
+		return
+	}
+
+	path, err := worktree.FindWorktreePathForBranch(branchName)
+	if err != nil || path == "" {
+		// Creation failed and has already been reported.
+		return
+	}
+
+	if err := worktree.OpenTmuxWindow(branchName, path); err != nil {
+		log.Warnf("could not open tmux window: %v", err)
+	}
+}
+
+// openInEditor launches the configured editor on the worktree of branchName.
+// Without an editor, the already printed path is all the user gets.
+func openInEditor(branchName string) {
+	editor := worktree.Editor()
+	if editor == nil {
+		log.Infof("No editor configured (WTGO_EDITOR, EDITOR or VISUAL); skipping --open.")
+		return
+	}
+
+	path, err := worktree.FindWorktreePathForBranch(branchName)
+	if err != nil || path == "" {
+		// Creation failed and has already been reported.
+		return
+	}
+
+	if err := worktree.OpenInEditor(editor, path); err != nil {
+		log.Warnf("could not open editor: %v", err)
+	}
+}
+
+// historySteps reports whether arg is `-` or `-<n>`, which switch to the
+// worktree 1 or n steps back in the history, and returns the step count.
+func historySteps(arg string) (int, bool) {
+	if arg == "-" {
+		return 1, true
+	}
+	digits, ok := strings.CutPrefix(arg, "-")
+	if !ok || digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return 0, false
+	}
+	n, err := strconv.Atoi(digits)
+	return n, err == nil
+}
+
+// moveHistoryArgs moves a `-<n>` argument behind `--`, so it reaches the
+// root command as a positional argument instead of being parsed as flags.
+func moveHistoryArgs(args []string) []string {
+	for i, arg := range args {
+		if arg == "--" {
+			return args
+		}
+		if _, ok := historySteps(arg); !ok || arg == "-" {
+			continue
+		}
+		moved := append(append([]string{}, args[:i]...), args[i+1:]...)
+		if !slices.Contains(moved, "--") {
+			moved = append(moved, "--")
+		}
+		return append(moved, arg)
+	}
+	return args
+}
 
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	args := moveHistoryArgs(os.Args[1:])
+	rootCmd.SetArgs(args)
+	// Commands only fail here on flags or arguments cobra rejects, before
+	// PersistentPreRun has applied --json.
+	if cmd, err := rootCmd.ExecuteC(); err != nil {
+		worktree.JSONErrors = jsonFlag || jsonRequested(args)
+		worktree.ReportError(worktree.CodeUsage, err, "Error: %v\n", err)
+		if !worktree.JSONErrors {
+			log.Errorf("Run '%s --help' for usage.", cmd.CommandPath())
+		}
+		exit(exitUsage)
+	}
+	// Some failures are only reported, not returned; still signal a timeout.
+	if git.TimedOut() {
+		exit(exitError)
+	}
+}
+
+// jsonRequested reports whether args turn on --json. Flag parsing stops at
+// the first flag cobra rejects, so a --json after it is only found here.
+func jsonRequested(args []string) bool {
+	requested := false
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if arg == "--json" {
+			requested = true
+		} else if value, ok := strings.CutPrefix(arg, "--json="); ok {
+			requested, _ = strconv.ParseBool(value)
+		}
 	}
+	return requested
 }
 
 // main is the entry point for the wtgogo application.
@@ -102,4 +936,53 @@ func init() {
 	// Add persistent flags here
 	rootCmd.PersistentFlags().BoolVarP(&removeFlag, "rm", "", false, "Remove a Git worktree and delete its branch")
 	rootCmd.PersistentFlags().BoolVarP(&forceFlag, "force", "f", false, "Force remove a Git worktree")
+	rootCmd.PersistentFlags().BoolVar(&keepBranchFlag, "keep-branch", false, "With --rm, remove only the worktree and keep its branch")
+	rootCmd.PersistentFlags().BoolVar(&renameFlag, "rename", false, "Rename a branch and move its worktree to match")
+	rootCmd.PersistentFlags().BoolVar(&moveFlag, "move", false, "Move the worktree of a branch to a new path")
+	rootCmd.PersistentFlags().BoolVar(&pruneFlag, "prune", false, "Drop git's records of worktrees whose directories are gone and report them")
+	rootCmd.PersistentFlags().StringVar(&allMergedIntoFlag, "all-merged-into", "", "With --rm, remove worktrees whose branches are merged into this ref, picking which to keep from a menu")
+	rootCmd.PersistentFlags().BoolVar(&mergedFlag, "merged", false, "With --rm, remove worktrees whose branches are merged into a base (default: the default branch)")
+	rootCmd.PersistentFlags().BoolVar(&staleFlag, "stale", false, "With --rm, remove worktrees whose directories no longer exist")
+	rootCmd.PersistentFlags().BoolVar(&dryRunFlag, "dry-run", false, "Print what a create or bulk removal would do without changing anything")
+	rootCmd.PersistentFlags().BoolVarP(&yesFlag, "yes", "y", false, "Answer yes to confirmation prompts")
+	rootCmd.PersistentFlags().BoolVar(&detachFlag, "detach", false, "Create a worktree with a detached HEAD at a commit-ish (with --rm, remove one by path)")
+	rootCmd.PersistentFlags().BoolVar(&orphanFlag, "orphan", false, "Create the worktree with a new orphan branch that has no history")
+	rootCmd.PersistentFlags().BoolVar(&stashFlag, "stash", false, "Stash changes in the current worktree before switching")
+	rootCmd.PersistentFlags().BoolVar(&fetchFirstFlag, "fetch-first", false, "Fetch all remotes before creating the worktree")
+	rootCmd.PersistentFlags().StringVar(&baseDirFlag, "base-dir", "", "Create a new worktree in this directory instead of the collection directory")
+	rootCmd.PersistentFlags().StringVar(&nameTemplateFlag, "name-template", "", "Name new worktree directories after this template, e.g. {repo}-{branch} (placeholders: {branch}, {repo}, {date}, {user})")
+	rootCmd.PersistentFlags().StringVar(&pathFlag, "path", "", "Print the worktree path of this branch, or nothing if it has none; with --json, report whether it exists")
+	rootCmd.PersistentFlags().StringVar(&prFlag, "pr", "", "Fetch this pull request number into branch pr-<n> and create its worktree")
+	rootCmd.PersistentFlags().BoolVar(&fetchFlag, "fetch", false, "Fetch the branch from origin before creating a worktree that tracks it")
+	rootCmd.PersistentFlags().StringVar(&fromFlag, "from", "", "Start a newly created branch at this ref instead of the current HEAD")
+	rootCmd.PersistentFlags().BoolVar(&noTrackFlag, "no-track", false, "Create a missing branch from HEAD even if origin has a branch of that name")
+	rootCmd.PersistentFlags().BoolVar(&noSwitchFlag, "no-switch", false, "Create or find the worktree without recording the current directory for 'wtgo -'")
+	rootCmd.PersistentFlags().BoolVar(&recurseSubmodulesFlag, "recurse-submodules", false, "Initialize the submodules of a new worktree, recursively")
+	rootCmd.PersistentFlags().BoolVar(&forceFreshFlag, "force-fresh", false, "Delete an existing branch and its worktree after confirmation, then recreate it from the base without tracking")
+	rootCmd.PersistentFlags().StringArrayVar(&copyFlag, "copy", nil, "Copy files matching this glob (relative to the worktree root) into a new worktree; repeatable")
+	rootCmd.PersistentFlags().StringVar(&copyFromFlag, "copy-from", "", "Copy the uncommitted changes of this branch's worktree into the new worktree")
+	rootCmd.PersistentFlags().BoolVar(&tmuxFlag, "tmux", false, "Open the worktree in a new tmux window (configure with wtgo.tmuxCommand)")
+	rootCmd.PersistentFlags().BoolVar(&openFlag, "open", false, "Open the worktree in $WTGO_EDITOR, $EDITOR or $VISUAL without waiting for it")
+	rootCmd.PersistentFlags().BoolVarP(&quietFlag, "quiet", "q", false, "Only report warnings and errors")
+	rootCmd.PersistentFlags().BoolVarP(&verboseFlag, "verbose", "v", false, "Also report debugging details such as the git commands run")
+	rootCmd.PersistentFlags().BoolVarP(&listPathsFlag, "list-paths", "p", false, "List worktrees as tab-separated branch and path pairs")
+	rootCmd.PersistentFlags().BoolVarP(&interactiveFlag, "interactive", "i", false, "Pick a worktree from a numbered menu on the terminal and print its path")
+	rootCmd.PersistentFlags().BoolVar(&currentFlag, "current", false, "Print the branch of the current worktree, or @<short commit> if HEAD is detached")
+	rootCmd.PersistentFlags().BoolVar(&statusFlag, "status", false, "Show every worktree's branch, uncommitted changes and ahead/behind counts")
+	// Local, so subcommands such as sync keep their own --all.
+	rootCmd.Flags().BoolVar(&allFlag, "all", false, "When listing, include branches matched by the .wtgoignore file")
+	rootCmd.Flags().BoolVar(&pullFlag, "pull", false, "Fast-forward the worktree of a branch (default: the current one) from its upstream")
+	rootCmd.Flags().BoolVar(&rebaseFlag, "rebase", false, "With --pull, rebase local commits onto the upstream instead")
+	rootCmd.PersistentFlags().BoolVar(&ageFlag, "age", false, "When listing, sort by last commit date, oldest first, and show how long ago it was")
+	rootCmd.PersistentFlags().StringVarP(&repoFlag, "repo", "C", "", "Run as if wtgo was started in this directory of a git repository")
+	rootCmd.PersistentFlags().BoolVar(&trackFlag, "track", false, "When listing, show how far each branch is ahead of or behind its upstream")
+	rootCmd.PersistentFlags().BoolVar(&lockedFlag, "locked", false, "When listing, only show worktrees locked by git")
+	rootCmd.PersistentFlags().BoolVar(&allBranchesFlag, "all-branches", false, "When listing, include local branches without a worktree")
+	rootCmd.PersistentFlags().BoolVar(&strictFlag, "strict", false, "When listing, exit with status 1 if there are no worktrees")
+	rootCmd.PersistentFlags().BoolVar(&jsonFlag, "json", false, "Report errors as JSON objects on stderr and list worktrees as JSON")
+	rootCmd.PersistentFlags().StringVar(&issueFlag, "issue", "", "Name the new branch after this issue id and record the association")
+	rootCmd.PersistentFlags().DurationVar(&timeoutFlag, "timeout", 0, "Abort the operation if git is still running after this duration in total, e.g. 30s (0 means no limit; unset, network commands time out after 60s)")
+	rootCmd.PersistentFlags().BoolVar(&relativeFlag, "relative", false, "Print resolved worktree paths relative to the current directory")
+	rootCmd.PersistentFlags().BoolVar(&print0Flag, "print0", false, "Terminate printed paths and listed branch names with a NUL byte")
+	rootCmd.PersistentFlags().StringVar(&cdFileFlag, "cd-file", "", "Also write the resulting worktree path to this file")
 }
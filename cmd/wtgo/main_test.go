@@ -0,0 +1,312 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHistorySteps(t *testing.T) {
+	tests := []struct {
+		arg    string
+		want   int
+		wantOK bool
+	}{
+		{"-", 1, true},
+		{"-1", 1, true},
+		{"-3", 3, true},
+		{"-20", 20, true},
+		{"-0", 0, true},
+		{"--", 0, false},
+		{"-x", 0, false},
+		{"-3x", 0, false},
+		{"-+3", 0, false},
+		{"--3", 0, false},
+		{"3", 0, false},
+		{"", 0, false},
+		{"feature", 0, false},
+		{"-99999999999999999999", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			got, ok := historySteps(tt.arg)
+			if ok != tt.wantOK || (ok && got != tt.want) {
+				t.Errorf("historySteps(%q) = %d, %v; want %d, %v", tt.arg, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestMoveHistoryArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"no history argument", []string{"--json", "feature"}, []string{"--json", "feature"}},
+		{"plain dash stays", []string{"-"}, []string{"-"}},
+		{"steps move behind --", []string{"-2"}, []string{"--", "-2"}},
+		{"steps after flags", []string{"--verbose", "-3"}, []string{"--verbose", "--", "-3"}},
+		{"existing -- is kept", []string{"-2", "--"}, []string{"--", "-2"}},
+		{"after -- untouched", []string{"--", "-2"}, []string{"--", "-2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := moveHistoryArgs(tt.args); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("moveHistoryArgs(%q) = %q, want %q", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLastNonEmptyLine(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"empty", "", ""},
+		{"only blank lines", "\n  \n\t\n", ""},
+		{"single line without newline", "main", "main"},
+		{"single line", "main\n", "main"},
+		{"last of several", "header\nfeat/a\nfeat/b\n", "feat/b"},
+		{"trailing blank lines", "feat/a\n\n  \n", "feat/a"},
+		{"CRLF", "header\r\nfeat/a\r\n", "feat/a"},
+		{"CRLF with trailing blank lines", "feat/a\r\n\r\n\r\n", "feat/a"},
+		{"surrounding whitespace", "  feat/a\t\n", "feat/a"},
+		{"marker is kept", "  main\n* feat/a\n", "* feat/a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := lastNonEmptyLine(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("lastNonEmptyLine(%q): %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("lastNonEmptyLine(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCleanPipedBranchName(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"feat/a", "feat/a"},
+		{"  feat/a  ", "feat/a"},
+		{"feat/a\r", "feat/a"},
+		{"* main", "main"},
+		{"*   main", "main"},
+		{"+ feat/a", "feat/a"},
+		{"✓ feat/a", "feat/a"},
+		{"*main", "*main"},
+		{"'feat/a'", "feat/a"},
+		{`"feat/a"`, "feat/a"},
+		{`"* main"`, "main"},
+		{`'feat/a"`, `'feat/a"`},
+		{`"`, `"`},
+		{"feat/*", "feat/*"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := cleanPipedBranchName(tt.input); got != tt.want {
+				t.Errorf("cleanPipedBranchName(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPipedBranchName(t *testing.T) {
+	// What `git branch | fzf | wtgo` hands over from a Windows tool.
+	input := "  main\r\n* feat/a\r\n\r\n"
+	line, err := lastNonEmptyLine(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cleanPipedBranchName(line); got != "feat/a" {
+		t.Errorf("piped branch name = %q, want %q", got, "feat/a")
+	}
+}
+
+func TestJSONRequested(t *testing.T) {
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{nil, false},
+		{[]string{"--bogus"}, false},
+		{[]string{"--json"}, true},
+		{[]string{"--bogus", "--json"}, true},
+		{[]string{"--json=true", "--bogus"}, true},
+		{[]string{"--json=false", "--bogus"}, false},
+		{[]string{"--json", "--json=0"}, false},
+		{[]string{"--", "--json"}, false},
+		{[]string{"--jsonx"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			if got := jsonRequested(tt.args); got != tt.want {
+				t.Errorf("jsonRequested(%q) = %v, want %v", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestMainHelperProcess is not a real test: TestRejectedFlagErrors runs the
+// test binary as wtgo through it, passing the arguments after "--".
+func TestMainHelperProcess(t *testing.T) {
+	if os.Getenv("WTGO_TEST_MAIN") == "" {
+		return
+	}
+	args := os.Args
+	for i, arg := range args {
+		if arg == "--" {
+			args = args[i+1:]
+			break
+		}
+	}
+	os.Args = append([]string{"wtgo"}, args...)
+	main()
+	os.Exit(0)
+}
+
+func TestRejectedFlagErrors(t *testing.T) {
+	run := func(t *testing.T, args ...string) (string, int) {
+		t.Helper()
+		cmd := exec.Command(os.Args[0], append([]string{"-test.run=^TestMainHelperProcess$", "--"}, args...)...)
+		cmd.Env = append(os.Environ(), "WTGO_TEST_MAIN=1")
+		var stdout, stderr strings.Builder
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		err := cmd.Run()
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			t.Fatalf("wtgo %q: %v, want a failure", args, err)
+		}
+		if stdout.Len() != 0 {
+			t.Errorf("wtgo %q wrote to stdout: %q", args, stdout.String())
+		}
+		return stderr.String(), exitErr.ExitCode()
+	}
+
+	for _, args := range [][]string{
+		{"--json", "--bogus"},
+		{"--bogus", "--json"},
+		{"--json", "--timeout=soon"},
+		{"pin", "--json"},
+	} {
+		t.Run(strings.Join(args, " "), func(t *testing.T) {
+			stderr, code := run(t, args...)
+			if code != exitUsage {
+				t.Errorf("exit code = %d, want %d", code, exitUsage)
+			}
+			var envelope struct {
+				Error   string `json:"error"`
+				Message string `json:"message"`
+			}
+			if err := json.Unmarshal([]byte(stderr), &envelope); err != nil {
+				t.Fatalf("stderr is not a single JSON object: %v\n%s", err, stderr)
+			}
+			if envelope.Error != "usage" || envelope.Message == "" {
+				t.Errorf("envelope = %+v, want a usage error with a message", envelope)
+			}
+		})
+	}
+
+	t.Run("plain", func(t *testing.T) {
+		stderr, code := run(t, "--bogus")
+		if code != exitUsage {
+			t.Errorf("exit code = %d, want %d", code, exitUsage)
+		}
+		if n := strings.Count(stderr, "unknown flag: --bogus"); n != 1 {
+			t.Errorf("error reported %d times, want once:\n%s", n, stderr)
+		}
+	})
+}
+
+// newCommandTest creates a temporary directory holding a git repository
+// "repo" with one commit on main and returns the directory and a function
+// that runs git, or wtgo if name is "wtgo", in it with stdin and returns its
+// stdout, failing the test if it fails. Neither the user's git config nor
+// their wtgo settings interfere. The test is skipped if git is not installed.
+func newCommandTest(t *testing.T) (string, func(stdin, name string, args ...string) string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := append(os.Environ(),
+		"WTGO_TEST_MAIN=1",
+		"GIT_CONFIG_NOSYSTEM=1",
+		"GIT_CONFIG_GLOBAL="+os.DevNull,
+		"GIT_AUTHOR_NAME=wtgo", "GIT_AUTHOR_EMAIL=wtgo@example.com",
+		"GIT_COMMITTER_NAME=wtgo", "GIT_COMMITTER_EMAIL=wtgo@example.com",
+		"WTGO_WORKTREE_DIR=", "WTGO_GLOBAL_ROOT=", "WTGO_POST_CREATE=",
+	)
+	run := func(stdin, name string, args ...string) string {
+		t.Helper()
+		if name == "wtgo" {
+			name, args = os.Args[0], append([]string{"-test.run=^TestMainHelperProcess$", "--"}, args...)
+		}
+		cmd := exec.Command(name, args...)
+		cmd.Dir = dir
+		cmd.Env = env
+		cmd.Stdin = strings.NewReader(stdin)
+		var stdout, stderr strings.Builder
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("%s %q: %v\n%s", name, args, err, stderr.String())
+		}
+		return stdout.String()
+	}
+	run("", "git", "init", "--quiet", "--initial-branch=main", "repo")
+	run("", "git", "-C", "repo", "commit", "--quiet", "--allow-empty", "-m", "initial")
+	return dir, run
+}
+
+func TestRepoFlagKeepsPathsRelativeToStartDir(t *testing.T) {
+	dir, run := newCommandTest(t)
+
+	if got, want := run("", "wtgo", "-C", "repo", "--base-dir", "wts", "--relative", "b2"), filepath.Join("wts", "b2"); got != want {
+		t.Errorf("created %q, want %q relative to the start directory", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "wts", "b2")); err != nil {
+		t.Errorf("worktree not created under the start directory: %v", err)
+	}
+
+	if got := run("", "wtgo", "-C", "repo", "--move", "b2", "moved", "--relative"); got != "moved" {
+		t.Errorf("moved to %q, want %q", got, "moved")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "moved")); err != nil {
+		t.Errorf("worktree not moved under the start directory: %v", err)
+	}
+}
+
+func TestPipedBranchHonoursStash(t *testing.T) {
+	dir, run := newCommandTest(t)
+	repo := filepath.Join(dir, "repo")
+	if err := os.WriteFile(filepath.Join(repo, "notes.txt"), []byte("wip\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	want := filepath.Join(dir, "repo.wt", "feat")
+	if got := run("feat\n", "wtgo", "-C", "repo", "--stash"); got != want {
+		t.Errorf("created %q, want %q", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(repo, "notes.txt")); !os.IsNotExist(err) {
+		t.Errorf("the change was not stashed (stat error %v)", err)
+	}
+	if stashes := run("", "git", "-C", "repo", "stash", "list"); !strings.Contains(stashes, "wtgo auto-stash") {
+		t.Errorf("stash list = %q, want the auto-stash", stashes)
+	}
+}
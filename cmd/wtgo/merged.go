@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sokinpui/wt-go/internal/log"
+	"github.com/sokinpui/wt-go/internal/worktree"
+)
+
+// removeMergedWorktrees implements `wtgo --rm --merged [<base>]`: it removes
+// the worktrees and branches of all branches merged into base.
+func removeMergedWorktrees(base string) {
+	base, merged, err := worktree.FindMergedWorktrees(base)
+	if err != nil {
+		worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+		exit(exitCodeOf(err))
+	}
+
+	if len(merged) == 0 {
+		log.Infof("No worktrees with branches merged into %s found.", base)
+		return
+	}
+
+	if dryRunFlag {
+		printRemovalPlan(merged)
+		return
+	}
+
+	log.Infof("Worktrees with branches merged into %s:", base)
+	for _, wt := range merged {
+		fmt.Printf("%s (%s)\n", wt.Branch, wt.Path)
+	}
+	if !worktree.Confirm(fmt.Sprintf("Remove these %d worktrees and their branches?", len(merged))) {
+		log.Infof("Nothing removed.")
+		return
+	}
+
+	removeAll(merged)
+}
+
+// removeWorktreesMergedInto implements `wtgo --rm --all-merged-into <ref>`:
+// it lists the worktrees of branches merged into ref and removes them, except
+// those deselected in a numbered menu, which --yes skips.
+func removeWorktreesMergedInto(ref string) {
+	ref, merged, err := worktree.FindMergedWorktrees(ref)
+	if err != nil {
+		worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+		exit(exitCodeOf(err))
+	}
+
+	if len(merged) == 0 {
+		log.Infof("No worktrees with branches merged into %s found.", ref)
+		return
+	}
+
+	if dryRunFlag {
+		printRemovalPlan(merged)
+		return
+	}
+
+	selected := merged
+	if !yesFlag {
+		log.Infof("Worktrees with branches merged into %s:", ref)
+		if selected, err = worktree.DeselectWorktrees(merged); err != nil {
+			worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+			exit(exitCodeOf(err))
+		}
+		if len(selected) == 0 {
+			log.Infof("Nothing removed.")
+			return
+		}
+	}
+
+	removeAll(selected)
+}
+
+// removeAll removes the given worktrees and their branches, prints how
+// many were, and exits with an error unless all of them were.
+func removeAll(worktrees []worktree.RemovableWorktree) {
+	removed := worktree.RemoveWorktrees(worktrees, forceFlag)
+	if len(removed) == 0 {
+		log.Infof("Removed 0 of %d worktrees.", len(worktrees))
+	} else {
+		log.Infof("Removed %d of %d worktrees: %s", len(removed), len(worktrees), strings.Join(removed, ", "))
+	}
+	if len(removed) != len(worktrees) {
+		exit(exitError)
+	}
+}
+
+// printRemovalPlan prints what a bulk removal of worktrees would do. Without
+// --force, worktrees with uncommitted changes would fail to be removed, so
+// they are listed under a separate heading instead.
+func printRemovalPlan(worktrees []worktree.RemovableWorktree) {
+	var dirty []worktree.RemovableWorktree
+	for _, wt := range worktrees {
+		if !forceFlag && wt.IsDirty() {
+			dirty = append(dirty, wt)
+			continue
+		}
+		fmt.Printf("would remove: %s (%s)\n", wt.Branch, wt.Path)
+	}
+	if len(dirty) == 0 {
+		return
+	}
+	fmt.Println("would skip, uncommitted changes (pass --force to remove):")
+	for _, wt := range dirty {
+		fmt.Printf("  %s (%s)\n", wt.Branch, wt.Path)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/sokinpui/wt-go/internal/log"
+	"github.com/sokinpui/wt-go/internal/worktree"
+	"github.com/spf13/cobra"
+)
+
+var noBrowseFlag bool
+
+var openPRCmd = &cobra.Command{
+	Use:   "open-pr",
+	Short: "Push the current branch to origin and open a pull request page for it",
+	Long: `open-pr pushes the branch of the current worktree to origin, setting its
+upstream, then prints the GitHub "compare" or GitLab "new merge request" URL
+for it and opens it in the browser.
+
+Usage:
+  wtgo open-pr              Push and open the pull request page
+  wtgo open-pr --no-browse  Push and only print the URL
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		prURL, err := worktree.PushForPullRequest()
+		if err != nil {
+			worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+			exit(exitCodeOf(err))
+		}
+
+		fmt.Println(prURL)
+		if noBrowseFlag {
+			return
+		}
+		if err := worktree.OpenInBrowser(prURL); err != nil {
+			log.Warnf("%v", err)
+		}
+	},
+}
+
+func init() {
+	openPRCmd.Flags().BoolVar(&noBrowseFlag, "no-browse", false, "Only print the pull request URL")
+	rootCmd.AddCommand(openPRCmd)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sokinpui/wt-go/internal/worktree"
+	"github.com/spf13/cobra"
+)
+
+var shellInitName string
+
+// posixShellFunction changes into the path wtgo hands over through --cd-file,
+// so listings and other output are printed as usual and an empty result never
+// turns into `cd ""`.
+const posixShellFunction = `NAME() {
+  local wtgo_tmp wtgo_out wtgo_status
+  wtgo_tmp=$(mktemp) || return
+  wtgo_out=$(command wtgo --cd-file "$wtgo_tmp" "$@")
+  wtgo_status=$?
+  if [ -s "$wtgo_tmp" ]; then
+    cd -- "$(cat "$wtgo_tmp")" || wtgo_status=$?
+  elif [ -n "$wtgo_out" ]; then
+    printf '%s\n' "$wtgo_out"
+  fi
+  rm -f -- "$wtgo_tmp"
+  return $wtgo_status
+}
+`
+
+const fishShellFunction = `function NAME --wraps wtgo
+    set -l wtgo_tmp (mktemp); or return
+    set -l wtgo_out (command wtgo --cd-file $wtgo_tmp $argv)
+    set -l wtgo_status $status
+    if test -s $wtgo_tmp
+        cd (cat $wtgo_tmp); or set wtgo_status $status
+    else if test (count $wtgo_out) -gt 0
+        printf '%s\n' $wtgo_out
+    end
+    rm -f $wtgo_tmp
+    return $wtgo_status
+end
+`
+
+// shellFunctionName matches names that are safe to splice into the function
+// definitions.
+var shellFunctionName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+var shellInitCmd = &cobra.Command{
+	Use:    "shell-init <bash|zsh|fish>",
+	Short:  "Print a shell function that runs wtgo and changes into the resulting worktree",
+	Hidden: true,
+	Long: `shell-init prints a shell function, named wt unless --name says otherwise,
+that runs wtgo with its arguments and changes into the worktree path wtgo
+resolves. Commands that resolve no path, such as listings, print their output
+as usual and leave the directory alone.
+
+Usage:
+  eval "$(wtgo shell-init bash)"     in ~/.bashrc
+  eval "$(wtgo shell-init zsh)"      in ~/.zshrc
+  wtgo shell-init fish | source      in ~/.config/fish/config.fish
+`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: []string{"bash", "zsh", "fish"},
+	Run: func(cmd *cobra.Command, args []string) {
+		if !shellFunctionName.MatchString(shellInitName) {
+			worktree.ReportError(worktree.CodeUsage, nil, "Error: '%s' is not a valid function name.\n", shellInitName)
+			exit(exitUsage)
+		}
+
+		var function string
+		switch args[0] {
+		case "bash", "zsh":
+			function = posixShellFunction
+		case "fish":
+			function = fishShellFunction
+		default:
+			worktree.ReportError(worktree.CodeUsage, nil, "Error: Unknown shell '%s'. Use 'bash', 'zsh' or 'fish'.\n", args[0])
+			exit(exitUsage)
+		}
+		fmt.Print(strings.Replace(function, "NAME", shellInitName, 1))
+	},
+}
+
+func init() {
+	shellInitCmd.Flags().StringVar(&shellInitName, "name", "wt", "Name of the shell function")
+	rootCmd.AddCommand(shellInitCmd)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/sokinpui/wt-go/internal/log"
+	"github.com/sokinpui/wt-go/internal/worktree"
+)
+
+// removeStaleWorktrees implements `wtgo --rm --stale`: it prunes worktrees
+// whose directories are gone and offers to delete their branches.
+func removeStaleWorktrees() {
+	stale, err := worktree.FindStaleWorktrees()
+	if err != nil {
+		worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+		exit(exitCodeOf(err))
+	}
+
+	if len(stale) == 0 {
+		log.Infof("No stale worktrees found.")
+		return
+	}
+
+	if dryRunFlag {
+		for _, wt := range stale {
+			if wt.Locked {
+				fmt.Printf("would keep worktree: %s (locked worktree)\n", wt.Path)
+			} else {
+				fmt.Printf("would prune worktree: %s\n", wt.Path)
+			}
+			if wt.Branch == "" {
+				continue
+			}
+			if reason := wt.BranchSkipReason(); reason != "" {
+				fmt.Printf("would keep branch: %s (%s)\n", wt.Branch, reason)
+				continue
+			}
+			fmt.Printf("would delete branch: %s (after confirmation)\n", wt.Branch)
+		}
+		return
+	}
+
+	log.Infof("Stale worktrees:")
+	branches := 0
+	for _, wt := range stale {
+		if wt.Branch == "" {
+			fmt.Printf("%s (detached)\n", wt.Path)
+			continue
+		}
+		fmt.Printf("%s (%s)\n", wt.Path, wt.Branch)
+		if wt.BranchSkipReason() == "" {
+			branches++
+		}
+	}
+
+	if err := worktree.PruneStaleWorktrees(stale); err != nil {
+		worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+		exit(exitCodeOf(err))
+	}
+
+	if branches == 0 {
+		return
+	}
+	if !worktree.Confirm(fmt.Sprintf("Also delete the %d branches of the stale worktrees?", branches)) {
+		log.Infof("Branches kept.")
+		return
+	}
+	if err := worktree.DeleteStaleBranches(stale, forceFlag); err != nil {
+		exit(exitError)
+	}
+}
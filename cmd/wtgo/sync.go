@@ -0,0 +1,66 @@
+package main
+
+import (
+	"github.com/sokinpui/wt-go/internal/worktree"
+	"github.com/spf13/cobra"
+)
+
+var syncAll bool
+var syncOptions worktree.SyncOptions
+
+var syncCmd = &cobra.Command{
+	Use:   "sync [branch]",
+	Short: "Update worktree branches from their upstreams",
+	Long: `sync pulls the upstream of a worktree's branch into it, fast-forward only
+unless --rebase is given. Worktrees with uncommitted changes are skipped
+unless --autostash is given, as are branches without an upstream.
+
+Usage:
+  wtgo sync                  Sync the current worktree
+  wtgo sync <branch>         Sync the worktree of <branch>
+  wtgo sync --all            Sync all worktrees except protected branches
+`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if syncAll {
+			if len(args) != 0 {
+				worktree.ReportError(worktree.CodeUsage, nil, "Error: The --all flag cannot be combined with a branch name.\n")
+				exit(exitUsage)
+			}
+			if err := worktree.SyncAllWorktrees(syncOptions); err != nil {
+				worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+				exit(exitCodeOf(err))
+			}
+			return
+		}
+
+		syncWorktree(args, syncOptions)
+	},
+}
+
+// syncWorktree syncs the worktree of the branch in args, or of the current
+// branch if args is empty, for `wtgo sync` and `wtgo --pull`.
+func syncWorktree(args []string, opts worktree.SyncOptions) {
+	var branchName string
+	if len(args) == 1 {
+		branchName = args[0]
+	} else {
+		current, err := worktree.CurrentBranch()
+		if err != nil {
+			worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+			exit(exitCodeOf(err))
+		}
+		branchName = current
+	}
+	if err := worktree.SyncWorktree(branchName, opts); err != nil {
+		worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+		exit(exitCodeOf(err))
+	}
+}
+
+func init() {
+	syncCmd.Flags().BoolVar(&syncAll, "all", false, "Sync every worktree except the protected and default branches")
+	syncCmd.Flags().BoolVar(&syncOptions.Rebase, "rebase", false, "Rebase local commits onto the upstream instead of fast-forwarding only")
+	syncCmd.Flags().BoolVar(&syncOptions.Autostash, "autostash", false, "Stash uncommitted changes around the update instead of skipping")
+	rootCmd.AddCommand(syncCmd)
+}
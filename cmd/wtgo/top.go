@@ -0,0 +1,31 @@
+package main
+
+import (
+	"github.com/sokinpui/wt-go/internal/worktree"
+	"github.com/spf13/cobra"
+)
+
+var topCmd = &cobra.Command{
+	Use:   "top",
+	Short: "Print the path of the main worktree, recording the current one for 'wtgo -'",
+	Long: `top prints the path of the main worktree, the one the repository was cloned
+or initialized in, so the shell wrapper can change to it. The current
+worktree is recorded first, so 'wtgo -' switches back.
+
+Usage:
+  cd "$(wtgo top)"
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		path, err := worktree.SwitchToMainWorktree()
+		if err != nil {
+			worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+			exit(exitCodeOf(err))
+		}
+		worktree.PrintPath(path)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(topCmd)
+}
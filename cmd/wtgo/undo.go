@@ -0,0 +1,30 @@
+package main
+
+import (
+	"github.com/sokinpui/wt-go/internal/worktree"
+	"github.com/spf13/cobra"
+)
+
+var undoRmCmd = &cobra.Command{
+	Use:   "undo-rm",
+	Short: "Restore the branch and worktree deleted by the most recent --rm",
+	Long: `undo-rm recreates the branch removed by the most recent 'wtgo --rm' at its
+recorded commit, restores its upstream, re-adds its worktree and prints the
+worktree path. Only the latest removal can be undone.
+
+The commits of a branch that was never pushed or merged elsewhere become
+unreachable when it is removed, and 'git gc' deletes them once they are older
+than gc.pruneExpire (two weeks by default). Undo soon after removing.
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := worktree.UndoLastRemoval(); err != nil {
+			worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+			exit(exitCodeOf(err))
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(undoRmCmd)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"github.com/sokinpui/wt-go/internal/worktree"
+	"github.com/spf13/cobra"
+)
+
+var whereCmd = &cobra.Command{
+	Use:   "where <branch>",
+	Short: "Print the worktree path of <branch>, or fail if it has none",
+	Long: `where prints the path of the worktree holding <branch> and exits 0.
+If there is no such worktree it prints nothing and exits 1. Nothing is created
+or recorded, and errors stay silent unless --verbose is given.
+
+Usage:
+  cd "$(wtgo where feature)"
+  if wtgo where feature >/dev/null; then ...; fi
+`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		path, err := worktree.FindWorktreePathForBranch(args[0])
+		if err != nil {
+			if verboseFlag {
+				worktree.ReportError(worktree.ErrorCodeOf(err), err, "Error: %v\n", err)
+			}
+			exit(exitCodeOf(err))
+		}
+		if path == "" {
+			if verboseFlag {
+				err := worktree.MissingWorktreeError(args[0])
+				worktree.ReportError(worktree.CodeNotFound, err, "Error: %v\n", err)
+			}
+			exit(exitError)
+		}
+		worktree.PrintPath(path)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(whereCmd)
+}
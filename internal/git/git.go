@@ -2,23 +2,294 @@ package git
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/sokinpui/wt-go/internal/log"
 )
 
+// ErrTimeout is wrapped by the error of a git invocation that was killed
+// because the deadline of the context set with SetContext passed, or the
+// timeout set with SetNetworkTimeout.
+var ErrTimeout = errors.New("operation timed out")
+
+// operation bounds all git invocations, e.g. with a deadline for the whole
+// wtgo run.
+var operation = context.Background()
+
+// networkTimeout bounds each invocation of a networkCommands command; zero
+// means no limit.
+var networkTimeout time.Duration
+
+// networkCommands are the git subcommands wtgo runs that talk to a remote
+// and so may hang, e.g. on a credential prompt. Long local commands such as
+// a checkout by `worktree add` are not bounded by networkTimeout.
+var networkCommands = map[string]bool{
+	"fetch":     true,
+	"ls-remote": true,
+	"pull":      true,
+	"push":      true,
+}
+
+// timedOut records whether any git invocation has been killed by a timeout.
+var timedOut bool
+
+// changes counts the git invocations that may have modified the repository.
+var changes int
+
+// readOnlyCommands are the git subcommands wtgo runs that never modify
+// branches or worktrees. Any other invocation counts as a change.
+var readOnlyCommands = map[string]bool{
+	"cat-file":     true,
+	"config":       true,
+	"diff":         true,
+	"for-each-ref": true,
+	"log":          true,
+	"ls-files":     true,
+	"merge-base":   true,
+	"remote":       true,
+	"rev-list":     true,
+	"rev-parse":    true,
+	"show-ref":     true,
+	"status":       true,
+	"symbolic-ref": true,
+	"version":      true,
+}
+
+// Changes returns how many git invocations so far may have modified the
+// repository's branches or worktrees, so callers can tell when information
+// read earlier may be out of date.
+func Changes() int {
+	return changes
+}
+
+// subcommandArgs returns args without leading `-C <dir>` and `-c <config>`
+// options, starting with the git subcommand.
+func subcommandArgs(args []string) []string {
+	for len(args) >= 2 && (args[0] == "-C" || args[0] == "-c") {
+		args = args[2:]
+	}
+	return args
+}
+
+// isReadOnly reports whether git invoked with args leaves branches and
+// worktrees alone.
+func isReadOnly(args []string) bool {
+	args = subcommandArgs(args)
+	if len(args) == 0 {
+		return true
+	}
+	if args[0] == "worktree" {
+		return len(args) > 1 && args[1] == "list"
+	}
+	return readOnlyCommands[args[0]]
+}
+
+// SetContext makes all later git invocations get killed once ctx is done,
+// in addition to the context each is given.
+func SetContext(ctx context.Context) {
+	operation = ctx
+}
+
+// SetNetworkTimeout makes each invocation of a git command that talks to a
+// remote, such as fetch, get killed once it has run for d. Zero removes the
+// limit.
+func SetNetworkTimeout(d time.Duration) {
+	networkTimeout = d
+}
+
+// isNetwork reports whether git invoked with args talks to a remote.
+func isNetwork(args []string) bool {
+	args = subcommandArgs(args)
+	return len(args) > 0 && networkCommands[args[0]]
+}
+
+// TimedOut reports whether any git invocation has been killed by a timeout.
+func TimedOut() bool {
+	return timedOut
+}
+
+// Error describes a failed git invocation, keeping git's stderr so callers
+// can tell failure kinds apart.
+type Error struct {
+	Args   []string
+	Stderr string
+	Err    error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("git command failed: %s %s: %v", strings.Join(e.Args, " "), e.Stderr, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns git's exit status, or -1 if git did not exit normally.
+func (e *Error) ExitCode() int {
+	var exitErr *exec.ExitError
+	if errors.As(e.Err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
 // Exec executes a git command with the given arguments.
-// It returns the combined stdout and stderr output, and an error if the command fails.
+// It returns the stdout output, and an error carrying stderr if the command fails.
 func Exec(args ...string) (string, error) {
-	cmd := exec.Command("git", args...)
+	return ExecContext(context.Background(), args...)
+}
+
+// ExecIn is like Exec, but runs git in dir, e.g. another worktree. An empty
+// dir means the current directory.
+func ExecIn(dir string, args ...string) (string, error) {
+	stdout, _, err := execSeparate(context.Background(), dir, args)
+	if err != nil {
+		return "", err
+	}
+	return stdout, nil
+}
+
+// ExecContext is like Exec, but kills git when ctx is done. The context set
+// with SetContext still applies.
+func ExecContext(ctx context.Context, args ...string) (string, error) {
+	stdout, _, err := execSeparate(ctx, "", args)
+	if err != nil {
+		return "", err
+	}
+	return stdout, nil
+}
+
+// ExecSeparate is like Exec, but also returns what git wrote to stderr, which
+// for some commands holds useful messages even on success. On failure, stderr
+// is returned as well as carried by the error.
+func ExecSeparate(args ...string) (stdout, stderr string, err error) {
+	return execSeparate(context.Background(), "", args)
+}
+
+func execSeparate(ctx context.Context, dir string, args []string) (string, string, error) {
 	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	err := run(ctx, dir, args, &stdout, &stderr, &stderr)
+	return stdout.String(), stderr.String(), err
+}
+
+// ExecStreaming executes a git command whose output is meant for the user,
+// such as a long checkout, and passes its stdout and stderr through to
+// stderr as it runs, keeping stdout free for wtgo's own results. When stderr
+// is a terminal, git writes to it directly so progress bars are preserved,
+// and the returned error carries no stderr text, since the user has already
+// seen it. Below info level nothing is streamed and it behaves like Exec.
+func ExecStreaming(args ...string) error {
+	return ExecStreamingIn("", args...)
+}
+
+// ExecStreamingIn is ExecStreaming run in dir, like ExecIn.
+func ExecStreamingIn(dir string, args ...string) error {
+	if !log.Enabled(log.LevelInfo) {
+		_, err := ExecIn(dir, args...)
+		return err
+	}
+
+	ctx := context.Background()
+	if isTerminal(os.Stderr) {
+		return run(ctx, dir, args, os.Stderr, os.Stderr, nil)
+	}
+	var stderr bytes.Buffer
+	tee := io.MultiWriter(os.Stderr, &stderr)
+	return run(ctx, dir, args, tee, tee, &stderr)
+}
+
+// run executes git with args in dir, or the current directory if dir is
+// empty, until ctx or the context set with SetContext is done, or a network
+// command has run for the timeout set with SetNetworkTimeout.
+// captured, if not nil, holds what git wrote to stderr for the returned error.
+func run(ctx context.Context, dir string, args []string, stdout, stderr io.Writer, captured *bytes.Buffer) error {
+	if dir != "" {
+		log.Debugf("exec: git %s (in %s)", strings.Join(args, " "), dir)
+	} else {
+		log.Debugf("exec: git %s", strings.Join(args, " "))
+	}
+
+	if !isReadOnly(args) {
+		changes++
+	}
+
+	if networkTimeout > 0 && isNetwork(args) {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, networkTimeout)
+		defer cancel()
+	}
+	if operation != context.Background() {
+		var cancel context.CancelCauseFunc
+		ctx, cancel = context.WithCancelCause(ctx)
+		defer cancel(nil)
+		stop := context.AfterFunc(operation, func() { cancel(context.Cause(operation)) })
+		defer stop()
+	}
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	// Hooks spawned by git may hold the output pipes open after git is killed.
+	cmd.WaitDelay = time.Second
 
 	err := cmd.Run()
 	if err != nil {
-		return "", fmt.Errorf("git command failed: %s %s: %w", strings.Join(args, " "), stderr.String(), err)
+		if errors.Is(context.Cause(ctx), context.DeadlineExceeded) {
+			timedOut = true
+			err = fmt.Errorf("%w: %v", ErrTimeout, err)
+		}
+		gitErr := &Error{Args: args, Err: err}
+		if captured != nil {
+			gitErr.Stderr = captured.String()
+		}
+		return gitErr
 	}
+	return nil
+}
+
+// isTerminal reports whether f is connected to a terminal.
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
 
-	return stdout.String(), nil
+// VersionAtLeast reports whether the installed git is at least major.minor.
+// It returns false if the version cannot be determined.
+func VersionAtLeast(major, minor int) bool {
+	output, err := Exec("version")
+	if err != nil {
+		return false
+	}
+
+	// Output looks like "git version 2.39.5" or "git version 2.39.3 (Apple Git-145)".
+	fields := strings.Fields(output)
+	if len(fields) < 3 {
+		return false
+	}
+	parts := strings.SplitN(fields[2], ".", 3)
+	if len(parts) < 2 {
+		return false
+	}
+	gotMajor, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return false
+	}
+	gotMinor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return false
+	}
+
+	if gotMajor != major {
+		return gotMajor > major
+	}
+	return gotMinor >= minor
 }
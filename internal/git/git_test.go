@@ -0,0 +1,180 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestRepos creates two git repositories, each with one commit, in a
+// temporary directory and returns their paths. The test is skipped if git
+// is not installed.
+func newTestRepos(t *testing.T) (string, string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var repos []string
+	for _, name := range []string{"one", "two"} {
+		repo := filepath.Join(dir, name)
+		for _, args := range [][]string{
+			{"init", "--quiet", "--initial-branch=" + name, repo},
+			{"-C", repo, "-c", "user.name=wtgo", "-c", "user.email=wtgo@example.com",
+				"commit", "--quiet", "--allow-empty", "-m", name},
+		} {
+			if output, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+				t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, output)
+			}
+		}
+		repos = append(repos, repo)
+	}
+	return repos[0], repos[1]
+}
+
+func TestExecInRunsInDir(t *testing.T) {
+	one, two := newTestRepos(t)
+	t.Chdir(one)
+
+	tests := []struct {
+		name string
+		dir  string
+		want string
+	}{
+		{"empty dir is the current directory", "", one},
+		{"current repository", one, one},
+		{"other repository", two, two},
+		{"git directory", filepath.Join(two, ".git"), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExecIn(tt.dir, "rev-parse", "--show-toplevel")
+			if tt.want == "" {
+				// Inside .git there is no work tree, which git reports as
+				// an error, proving it ran there.
+				if err == nil {
+					t.Errorf("ExecIn(%q) = %q, want an error", tt.dir, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExecIn(%q): %v", tt.dir, err)
+			}
+			if got = filepath.FromSlash(strings.TrimSpace(got)); got != tt.want {
+				t.Errorf("ExecIn(%q) ran in %q, want %q", tt.dir, got, tt.want)
+			}
+		})
+	}
+
+	// The override is per call: the current directory is left alone.
+	if wd, err := os.Getwd(); err != nil || wd != one {
+		t.Errorf("working directory = %q, %v; want %q", wd, err, one)
+	}
+}
+
+func TestExecIsExecInCurrentDir(t *testing.T) {
+	one, _ := newTestRepos(t)
+	t.Chdir(one)
+
+	fromExec, err := Exec("symbolic-ref", "--short", "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromExecIn, err := ExecIn("", "symbolic-ref", "--short", "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fromExec != fromExecIn || strings.TrimSpace(fromExec) != "one" {
+		t.Errorf("Exec = %q, ExecIn(\"\") = %q, want both %q", fromExec, fromExecIn, "one\n")
+	}
+}
+
+func TestExecInError(t *testing.T) {
+	one, two := newTestRepos(t)
+	t.Chdir(one)
+
+	_, err := ExecIn(two, "rev-parse", "--verify", "refs/heads/one")
+	var gitErr *Error
+	if !errors.As(err, &gitErr) {
+		t.Fatalf("ExecIn error = %v, want a *git.Error", err)
+	}
+	// The directory is not passed as -C, so it does not show up in the args.
+	if want := []string{"rev-parse", "--verify", "refs/heads/one"}; !slices.Equal(gitErr.Args, want) {
+		t.Errorf("Args = %q, want %q", gitErr.Args, want)
+	}
+	if gitErr.ExitCode() != 128 {
+		t.Errorf("ExitCode() = %d, want 128", gitErr.ExitCode())
+	}
+	if !strings.Contains(gitErr.Stderr, "fatal") {
+		t.Errorf("Stderr = %q, want git's message", gitErr.Stderr)
+	}
+
+	if _, err := ExecIn(filepath.Join(two, "missing"), "status"); err == nil {
+		t.Error("ExecIn in a missing directory succeeded")
+	}
+}
+
+func TestSetContextBoundsAllInvocations(t *testing.T) {
+	one, _ := newTestRepos(t)
+	t.Chdir(one)
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	SetContext(ctx)
+	t.Cleanup(func() {
+		SetContext(context.Background())
+		timedOut = false
+	})
+
+	// Each nap fits the deadline on its own, but not both together.
+	nap := []string{"-c", "alias.nap=!sleep 0.2", "nap"}
+	if _, err := Exec(nap...); err != nil {
+		t.Fatalf("first nap: %v", err)
+	}
+	if _, err := Exec(nap...); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("second nap error = %v, want ErrTimeout", err)
+	}
+	if !TimedOut() {
+		t.Error("TimedOut() = false after the deadline killed git")
+	}
+}
+
+func TestNetworkTimeoutOnlyBoundsNetworkCommands(t *testing.T) {
+	for _, tt := range []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"fetch", "--all"}, true},
+		{[]string{"-C", "dir", "ls-remote", "origin"}, true},
+		{[]string{"-c", "core.x=y", "push"}, true},
+		{[]string{"worktree", "add", "path", "branch"}, false},
+		{[]string{"submodule", "update", "--init"}, false},
+		{nil, false},
+	} {
+		if got := isNetwork(tt.args); got != tt.want {
+			t.Errorf("isNetwork(%q) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+
+	one, _ := newTestRepos(t)
+	t.Chdir(one)
+	SetNetworkTimeout(50 * time.Millisecond)
+	t.Cleanup(func() {
+		SetNetworkTimeout(0)
+		timedOut = false
+	})
+	if _, err := Exec("-c", "alias.nap=!sleep 0.2", "nap"); err != nil {
+		t.Errorf("a local command was bounded by the network timeout: %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+// Package log writes wtgo's diagnostic messages to stderr, dropping those
+// below the configured level. Stdout is reserved for command results such
+// as worktree paths.
+package log
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Level orders messages by importance.
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// level is the lowest level that is written.
+var level = LevelInfo
+
+// out receives all messages.
+var out io.Writer = os.Stderr
+
+// SetLevel sets the lowest level that is written.
+func SetLevel(l Level) {
+	level = l
+}
+
+// Enabled reports whether messages at l are written.
+func Enabled(l Level) bool {
+	return l >= level
+}
+
+// Writer returns where output of child processes at l should go: the
+// message stream if l is enabled, or a writer discarding everything.
+func Writer(l Level) io.Writer {
+	if !Enabled(l) {
+		return io.Discard
+	}
+	return out
+}
+
+// Debugf writes details useful when tracking down a problem, such as the git
+// commands being run.
+func Debugf(format string, args ...any) {
+	logf(LevelDebug, "", format, args...)
+}
+
+// Infof writes progress messages.
+func Infof(format string, args ...any) {
+	logf(LevelInfo, "", format, args...)
+}
+
+// Warnf writes a problem that does not stop the operation, prefixed with
+// "Warning: ".
+func Warnf(format string, args ...any) {
+	logf(LevelWarn, "Warning: ", format, args...)
+}
+
+// Errorf writes a failure. Unlike Warnf it adds no prefix, since error
+// messages are worded by their callers.
+func Errorf(format string, args ...any) {
+	logf(LevelError, "", format, args...)
+}
+
+func logf(l Level, prefix, format string, args ...any) {
+	if !Enabled(l) {
+		return
+	}
+	message := prefix + fmt.Sprintf(format, args...)
+	if !strings.HasSuffix(message, "\n") {
+		message += "\n"
+	}
+	fmt.Fprint(out, message)
+}
@@ -0,0 +1,162 @@
+package worktree
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/sokinpui/wt-go/internal/git"
+	"github.com/sokinpui/wt-go/internal/log"
+)
+
+// ListBranchesByRecency prints local branches sorted by committer date, most
+// recent first. Like `git branch`, branches that already have a worktree are
+// prefixed with "+ " and all others are indented by two spaces.
+func ListBranchesByRecency() error {
+	output, err := git.Exec("for-each-ref", "--sort=-committerdate", "--format=%(refname:short)", "refs/heads")
+	if err != nil {
+		return fmt.Errorf("listing branches: %w", err)
+	}
+
+	withWorktree, err := worktreeBranches()
+	if err != nil {
+		return fmt.Errorf("listing worktrees: %w", err)
+	}
+
+	for _, branch := range strings.Split(output, "\n") {
+		branch = strings.TrimSpace(branch)
+		if branch == "" {
+			continue
+		}
+		if withWorktree[branch] {
+			fmt.Printf("+ %s\n", branch)
+		} else {
+			fmt.Printf("  %s\n", branch)
+		}
+	}
+	return nil
+}
+
+// ListAllBranches prints every local branch in name order, marking those
+// that have a worktree with "✓ " and indenting the others by two spaces.
+// Branches associated with an issue show it in brackets, as in ListWorktrees.
+func ListAllBranches() error {
+	output, err := git.Exec("for-each-ref", "--sort=refname", "--format=%(refname:short)", "refs/heads")
+	if err != nil {
+		return fmt.Errorf("listing branches: %w", err)
+	}
+
+	withWorktree, err := worktreeBranches()
+	if err != nil {
+		return fmt.Errorf("listing worktrees: %w", err)
+	}
+
+	issues, err := branchIssues()
+	if err != nil {
+		log.Warnf("could not read branch issues: %v", err)
+	}
+
+	fmt.Fprintln(os.Stdout, "Git branches (✓ = has worktree):")
+	for _, branch := range strings.Split(output, "\n") {
+		branch = strings.TrimSpace(branch)
+		if branch == "" {
+			continue
+		}
+		line := "  " + branch
+		if withWorktree[branch] {
+			line = "✓ " + branch
+		}
+		if issue := issues[branch]; issue != "" {
+			line += " [" + issue + "]"
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
+
+// worktreeBranches returns the set of branches checked out in some worktree.
+func worktreeBranches() (map[string]bool, error) {
+	entries, err := listWorktrees()
+	if err != nil {
+		return nil, err
+	}
+
+	branches := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.Branch != "" {
+			branches[entry.Branch] = true
+		}
+	}
+	return branches, nil
+}
+
+// upstreamTracks maps each local branch with an upstream to how it compares,
+// as git words it in one for-each-ref call: "[ahead 1, behind 2]", "[gone]"
+// for a deleted upstream, or "" when the two are in sync.
+func upstreamTracks() (map[string]string, error) {
+	output, err := git.Exec("for-each-ref", "--format=%(refname:short) %(upstream:track)", "refs/heads")
+	if err != nil {
+		return nil, fmt.Errorf("listing branch upstreams: %w", err)
+	}
+	tracks := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		branch, track, _ := strings.Cut(strings.TrimSpace(line), " ")
+		if branch != "" {
+			tracks[branch] = track
+		}
+	}
+	return tracks, nil
+}
+
+// commitAges returns the local branches ordered by the date of their last
+// commit, oldest first, and maps each to that date relative to now, e.g.
+// "3 weeks ago", all from one for-each-ref call.
+func commitAges() ([]string, map[string]string, error) {
+	output, err := git.Exec("for-each-ref", "--sort=committerdate", "--format=%(refname:short) %(committerdate:relative)", "refs/heads")
+	if err != nil {
+		return nil, nil, fmt.Errorf("listing branch commit dates: %w", err)
+	}
+	var order []string
+	ages := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		branch, age, _ := strings.Cut(strings.TrimSpace(line), " ")
+		if branch != "" {
+			order = append(order, branch)
+			ages[branch] = age
+		}
+	}
+	return order, ages, nil
+}
+
+// LocalBranchNames returns the names of all local branches in name order.
+func LocalBranchNames() ([]string, error) {
+	output, err := git.Exec("for-each-ref", "--sort=refname", "--format=%(refname:short)", "refs/heads")
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, branch := range strings.Split(output, "\n") {
+		if branch = strings.TrimSpace(branch); branch != "" {
+			names = append(names, branch)
+		}
+	}
+	return names, nil
+}
+
+// WorktreeBranchNames returns the names of the branches checked out in some
+// worktree, in name order.
+func WorktreeBranchNames() ([]string, error) {
+	branches, err := worktreeBranches()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(branches))
+	for branch := range branches {
+		names = append(names, branch)
+	}
+	sort.Strings(names)
+	return names, nil
+}
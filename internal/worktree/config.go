@@ -0,0 +1,17 @@
+package worktree
+
+import (
+	"strings"
+
+	"github.com/sokinpui/wt-go/internal/git"
+)
+
+// configValue returns the value of a git config key such as `wtgo.tmuxCommand`,
+// or an empty string if it is unset.
+func configValue(key string) string {
+	value, err := git.Exec("config", "--get", key)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(value)
+}
@@ -0,0 +1,203 @@
+package worktree
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sokinpui/wt-go/internal/git"
+	"github.com/sokinpui/wt-go/internal/log"
+)
+
+// CopyChanges applies the uncommitted changes to tracked files in the
+// worktree of srcBranch, staged or not, to the worktree of dstBranch. The
+// destination must be clean. Untracked files are not copied; a warning lists
+// how many were left behind. If the two worktrees are based on different
+// commits the patch may not apply, in which case nothing is changed.
+func CopyChanges(srcBranch, dstBranch string) error {
+	srcPath, err := FindWorktreePathForBranch(srcBranch)
+	if err != nil {
+		return fmt.Errorf("finding worktree for branch '%s': %w", srcBranch, err)
+	}
+	if srcPath == "" {
+		return MissingWorktreeError(srcBranch)
+	}
+	dstPath, err := FindWorktreePathForBranch(dstBranch)
+	if err != nil {
+		return fmt.Errorf("finding worktree for branch '%s': %w", dstBranch, err)
+	}
+	if dstPath == "" {
+		return MissingWorktreeError(dstBranch)
+	}
+	if canonicalPath(srcPath) == canonicalPath(dstPath) {
+		return fmt.Errorf("cannot copy changes of branch '%s' onto itself", srcBranch)
+	}
+
+	status, err := git.ExecIn(dstPath, "status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return fmt.Errorf("checking for changes in '%s': %w", dstPath, err)
+	}
+	if strings.TrimSpace(status) != "" {
+		return fmt.Errorf("worktree '%s' has uncommitted changes; not copying changes into it", dstPath)
+	}
+
+	untracked, err := git.ExecIn(srcPath, "ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return fmt.Errorf("listing untracked files in '%s': %w", srcPath, err)
+	}
+	if n := len(strings.Fields(untracked)); n > 0 {
+		log.Warnf("%d untracked file(s) in '%s' are not copied; run `git -C %s status` to see them", n, srcPath, srcPath)
+	}
+
+	patch, err := git.ExecIn(srcPath, "diff", "--binary", "HEAD")
+	if err != nil {
+		return fmt.Errorf("reading changes in '%s': %w", srcPath, err)
+	}
+	if strings.TrimSpace(patch) == "" {
+		log.Infof("No uncommitted changes to copy from '%s'.", srcPath)
+		return nil
+	}
+
+	srcHead, _ := git.ExecIn(srcPath, "rev-parse", "HEAD")
+	dstHead, _ := git.ExecIn(dstPath, "rev-parse", "HEAD")
+	sameBase := strings.TrimSpace(srcHead) == strings.TrimSpace(dstHead)
+	if !sameBase {
+		log.Warnf("branches '%s' and '%s' point at different commits; the changes may not apply cleanly", srcBranch, dstBranch)
+	}
+
+	patchFile, err := os.CreateTemp("", "wtgo-copy-*.patch")
+	if err != nil {
+		return fmt.Errorf("creating patch file: %w", err)
+	}
+	defer os.Remove(patchFile.Name())
+	_, err = patchFile.WriteString(patch)
+	if closeErr := patchFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("writing patch file: %w", err)
+	}
+
+	if _, err := git.ExecIn(dstPath, "apply", "--whitespace=nowarn", patchFile.Name()); err != nil {
+		if !sameBase {
+			return fmt.Errorf("changes from '%s' conflict with branch '%s', which is based on a different commit; nothing was copied: %w", srcBranch, dstBranch, err)
+		}
+		return fmt.Errorf("applying changes from '%s': %w", srcPath, err)
+	}
+	log.Infof("changes copy: %s -> %s", srcPath, dstPath)
+	return nil
+}
+
+// copyMatchingFiles copies the files in the current worktree matching
+// patterns to the same relative paths in the worktree at dstPath.
+// Directories are copied recursively and symlinks are recreated as such.
+// Files already present in the destination are left alone, and matches
+// outside the current worktree, such as `..`, are skipped. Failures are
+// only warned about.
+func copyMatchingFiles(patterns []string, dstPath string) {
+	if len(patterns) == 0 {
+		return
+	}
+
+	top, err := git.Exec("rev-parse", "--show-toplevel")
+	if err != nil {
+		log.Warnf("not copying files: could not determine the current worktree: %v", err)
+		return
+	}
+	srcPath := filepath.FromSlash(strings.TrimSpace(top))
+	if canonicalPath(srcPath) == canonicalPath(dstPath) {
+		return
+	}
+
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(filepath.Join(srcPath, pattern))
+		if err != nil {
+			log.Warnf("invalid copy pattern '%s': %v", pattern, err)
+			continue
+		}
+		for _, match := range matches {
+			rel, err := filepath.Rel(srcPath, match)
+			if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				continue
+			}
+			dst := filepath.Join(dstPath, rel)
+			if info, err := os.Lstat(dst); err == nil && !info.IsDir() {
+				log.Infof("file copy skip: %s (already exists)", rel)
+				continue
+			}
+			if err := copyPath(match, dst); err != nil {
+				log.Warnf("could not copy '%s': %v", rel, err)
+				continue
+			}
+			log.Infof("file copy: %s", rel)
+		}
+	}
+}
+
+// copyPath copies the file, directory or symlink at src to dst, skipping
+// anything that already exists at the destination.
+func copyPath(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if _, err := os.Lstat(target); err == nil {
+			if d.IsDir() && path != src {
+				return filepath.SkipDir
+			}
+			if !d.IsDir() {
+				return nil
+			}
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case info.Mode().IsRegular():
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			return copyRegularFile(path, target, info.Mode().Perm())
+		}
+		return nil // Sockets, devices and the like are not copied.
+	})
+}
+
+func copyRegularFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
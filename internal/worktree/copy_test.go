@@ -0,0 +1,40 @@
+package worktree
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCopyMatchingFilesStaysInsideWorktree(t *testing.T) {
+	repo := newTestRepo(t)
+	dir := filepath.Dir(repo)
+	dst := filepath.Join(dir, "repo.wt", "dst")
+	runGit(t, repo, "worktree", "add", "--quiet", "-b", "dst", dst)
+	for path, content := range map[string]string{
+		filepath.Join(repo, ".env"):      "KEY=1\n",
+		filepath.Join(dir, "secret.txt"): "outside\n",
+	} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir(repo)
+
+	copyMatchingFiles([]string{"..", "../*", "../secret.txt", ".", ".env"}, dst)
+
+	if _, err := os.Stat(filepath.Join(dst, ".env")); err != nil {
+		t.Errorf(".env was not copied: %v", err)
+	}
+	// A match of `..` would be copied to the destination's parent, and one
+	// of `../*` next to the destination.
+	for _, path := range []string{
+		filepath.Join(dir, "repo.wt", "secret.txt"),
+		filepath.Join(dir, "repo.wt", "repo"),
+		filepath.Join(dst, "secret.txt"),
+	} {
+		if _, err := os.Lstat(path); !os.IsNotExist(err) {
+			t.Errorf("%s was created by copying from outside the worktree", path)
+		}
+	}
+}
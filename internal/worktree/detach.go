@@ -0,0 +1,155 @@
+package worktree
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/sokinpui/wt-go/internal/git"
+	"github.com/sokinpui/wt-go/internal/log"
+)
+
+// detachedPrefix starts the directory names of detached worktrees, keeping
+// them apart from branch worktrees in the collection directory.
+const detachedPrefix = "detached-"
+
+// detachedWorktreePath returns where the detached worktree for commitish is
+// created: `detached-<commit-ish>` in the collection directory, with slashes
+// handled as for branch names.
+func detachedWorktreePath(commitish string) (string, error) {
+	return newWorktreePathForBranch(detachedPrefix + commitish)
+}
+
+// CreateDetachedWorktree creates a worktree with a detached HEAD at
+// commitish, without creating a branch, and prints its path.
+func CreateDetachedWorktree(commitish string) error {
+	if commitish == "" {
+		return fmt.Errorf("%w: commit-ish cannot be empty", ErrInvalidBranch)
+	}
+	if _, err := git.Exec("rev-parse", "--verify", "--quiet", commitish+"^{commit}"); err != nil {
+		return fmt.Errorf("%w: '%s' does not resolve to a commit", ErrInvalidBranch, commitish)
+	}
+
+	unlock, err := lockRepo()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	newWorktreePath, err := detachedWorktreePath(commitish)
+	if err != nil {
+		return err
+	}
+	if pathExists(newWorktreePath) {
+		return fmt.Errorf("%w: '%s'; remove it with `wtgo --rm --detach %s` first", ErrWorktreeExists, newWorktreePath, commitish)
+	}
+
+	if err := saveCurrentWorktreeState(); err != nil {
+		log.Warnf("could not save current worktree state: %v", err)
+	}
+
+	log.Infof("worktree create: %s (detached at %s)", newWorktreePath, commitish)
+	log.Infof("Detached worktrees are named %s<commit-ish>, with '/' flattened to '_' unless WTGO_SANITIZE=nested.", detachedPrefix)
+
+	if err := ensureParentDir(newWorktreePath); err != nil {
+		return err
+	}
+
+	if err := git.ExecStreaming("worktree", "add", "--detach", newWorktreePath, commitish); err != nil {
+		rollbackPartialWorktree(newWorktreePath, false, "")
+		return fmt.Errorf("creating detached worktree at '%s': %w", commitish, err)
+	}
+	unlock()
+	if err := runPostCreateHook(newWorktreePath, ""); err != nil {
+		log.Warnf("%v", err)
+	}
+	PrintPath(newWorktreePath)
+	return nil
+}
+
+// RemoveDetachedWorktree removes a worktree with a detached HEAD, given by
+// its path or by the commit-ish it was created from with
+// CreateDetachedWorktree. Hooks run as for RemoveWorktreeAndBranch, with an
+// empty branch name.
+func RemoveDetachedWorktree(target string, force bool) error {
+	if target == "" {
+		return fmt.Errorf("a worktree path or commit-ish is required")
+	}
+
+	unlock, err := lockRepo()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	worktreePath, detached, err := findDetachedWorktree(target)
+	if err != nil {
+		return err
+	}
+	if worktreePath == "" {
+		return fmt.Errorf("%w: no worktree at '%s' or created for commit-ish '%s'", ErrWorktreeNotFound, target, target)
+	}
+	if !detached {
+		return fmt.Errorf("'%s' has a branch checked out; remove it with `wtgo --rm <branch>`", worktreePath)
+	}
+
+	if locked, reason, err := worktreeLock(worktreePath); err != nil {
+		return err
+	} else if locked {
+		if reason == "" {
+			reason = "no reason given"
+		}
+		return fmt.Errorf("%w: '%s' (%s); run `git worktree unlock %s` first", ErrWorktreeLocked, worktreePath, reason, worktreePath)
+	}
+
+	if force {
+		if err := confirmForcedRemoval(worktreePath); err != nil {
+			return err
+		}
+	}
+
+	if err := runHook("pre-remove", worktreePath, ""); err != nil {
+		return fmt.Errorf("%w; removal of '%s' aborted", err, worktreePath)
+	}
+
+	removeArgs := []string{"worktree", "remove"}
+	if force {
+		removeArgs = append(removeArgs, "--force")
+	}
+	removeArgs = append(removeArgs, worktreePath)
+	if _, err := git.Exec(removeArgs...); err != nil {
+		return fmt.Errorf("removing worktree '%s': %w", worktreePath, err)
+	}
+	log.Infof("worktree remove: %s", worktreePath)
+	removeEmptyParents(worktreePath)
+
+	unlock()
+	if err := runHook("post-remove", worktreePath, ""); err != nil {
+		log.Warnf("%v", err)
+	}
+	return nil
+}
+
+// findDetachedWorktree looks up the worktree at path target, falling back to
+// the detached worktree created for commit-ish target. It returns the
+// worktree's path as git records it, or an empty string if there is none,
+// and whether its HEAD is detached.
+func findDetachedWorktree(target string) (string, bool, error) {
+	entries, err := listWorktrees()
+	if err != nil {
+		return "", false, err
+	}
+
+	candidates := []string{canonicalPath(target)}
+	if path, err := detachedWorktreePath(target); err == nil {
+		candidates = append(candidates, canonicalPath(path))
+	}
+
+	for _, candidate := range candidates {
+		for _, entry := range entries {
+			if canonicalPath(entry.Path) == candidate {
+				return filepath.Clean(entry.Path), entry.Detached, nil
+			}
+		}
+	}
+	return "", false, nil
+}
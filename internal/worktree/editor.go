@@ -0,0 +1,35 @@
+package worktree
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// editorVars are the environment variables naming the editor for --open, in
+// order of preference.
+var editorVars = []string{"WTGO_EDITOR", "EDITOR", "VISUAL"}
+
+// Editor returns the editor command configured in the environment, split on
+// whitespace so values such as `code -n` work, or nil if none is set.
+func Editor() []string {
+	for _, name := range editorVars {
+		if fields := strings.Fields(os.Getenv(name)); len(fields) != 0 {
+			return fields
+		}
+	}
+	return nil
+}
+
+// OpenInEditor starts editor with path as its last argument and returns
+// without waiting for it, so wtgo exits while the editor keeps running.
+func OpenInEditor(editor []string, path string) error {
+	args := append(append([]string{}, editor[1:]...), path)
+	cmd := exec.Command(editor[0], args...)
+	cmd.Dir = path
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("starting %s: %w", editor[0], err)
+	}
+	return cmd.Process.Release()
+}
@@ -0,0 +1,48 @@
+package worktree
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/sokinpui/wt-go/internal/git"
+)
+
+// EnvVar is a single name/value pair exported by `wtgo env`.
+type EnvVar struct {
+	Name  string
+	Value string
+}
+
+// EnvVars returns the wtgo-derived environment for the current worktree:
+//
+//	WTGO_REPO_ROOT       root of the main repository
+//	WTGO_BRANCH          branch checked out in the current worktree (empty when detached)
+//	WTGO_WORKTREE_PATH   top-level directory of the current worktree
+//	WTGO_COLLECTION_DIR  directory where wtgo creates new worktrees
+func EnvVars() ([]EnvVar, error) {
+	repoRoot, err := mainRepoRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	worktreeDir, err := git.Exec("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("determining current worktree: %w", err)
+	}
+
+	// symbolic-ref fails quietly on a detached HEAD, which we report as no branch.
+	branch, _ := git.Exec("symbolic-ref", "--quiet", "--short", "HEAD")
+
+	collection, err := collectionDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return []EnvVar{
+		{Name: "WTGO_REPO_ROOT", Value: repoRoot},
+		{Name: "WTGO_BRANCH", Value: strings.TrimSpace(branch)},
+		{Name: "WTGO_WORKTREE_PATH", Value: filepath.FromSlash(strings.TrimSpace(worktreeDir))},
+		{Name: "WTGO_COLLECTION_DIR", Value: collection},
+	}, nil
+}
@@ -0,0 +1,161 @@
+package worktree
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sokinpui/wt-go/internal/git"
+	"github.com/sokinpui/wt-go/internal/log"
+)
+
+// ErrorCode identifies a kind of failure in machine-readable error output.
+type ErrorCode string
+
+const (
+	CodeError                     ErrorCode = "error"
+	CodeUsage                     ErrorCode = "usage"
+	CodeInvalidBranch             ErrorCode = "invalid_branch"
+	CodeProtectedBranch           ErrorCode = "protected_branch"
+	CodeNotFound                  ErrorCode = "not_found"
+	CodeNotARepo                  ErrorCode = "not_a_repo"
+	CodeBranchCheckedOutElsewhere ErrorCode = "branch_checked_out_elsewhere"
+	CodeDirtyWorktree             ErrorCode = "dirty_worktree"
+	CodeUnmergedBranch            ErrorCode = "unmerged_branch"
+	CodeLocked                    ErrorCode = "locked"
+	CodeTimeout                   ErrorCode = "timeout"
+	CodeForeignState              ErrorCode = "foreign_state"
+	CodeHookFailed                ErrorCode = "hook_failed"
+	CodeWorktreeLocked            ErrorCode = "worktree_locked"
+	CodeWorktreeExists            ErrorCode = "worktree_exists"
+	CodeGitFailure                ErrorCode = "git_failure"
+)
+
+// ErrInvalidBranch is wrapped by errors about branch names or refs that
+// cannot be used.
+var ErrInvalidBranch = errors.New("invalid branch")
+
+// ErrEmptyBranch is returned for an empty branch name. It wraps
+// ErrInvalidBranch.
+var ErrEmptyBranch = fmt.Errorf("%w: branch name cannot be empty", ErrInvalidBranch)
+
+// ErrNotGitRepo is wrapped by errors about running outside a git repository.
+var ErrNotGitRepo = errors.New("not a git repository")
+
+// ErrWorktreeNotFound is wrapped by errors about a branch or path that has
+// no worktree.
+var ErrWorktreeNotFound = errors.New("worktree not found")
+
+// ErrWorktreeExists is wrapped by errors about a new worktree's directory
+// already being taken by other files.
+var ErrWorktreeExists = errors.New("worktree path already exists")
+
+// ErrDirtyWorktree is wrapped by errors about refusing to discard a
+// worktree's uncommitted changes.
+var ErrDirtyWorktree = errors.New("worktree has uncommitted changes")
+
+// ErrUnmergedBranch is wrapped by errors about refusing to delete a branch
+// whose commits are not merged, as `git branch -d` would.
+var ErrUnmergedBranch = errors.New("branch is not fully merged")
+
+// JSONErrors makes ReportError emit JSON objects instead of human-readable text.
+var JSONErrors bool
+
+// jsonError is the shape of an error emitted with JSONErrors set.
+type jsonError struct {
+	Error       ErrorCode `json:"error"`
+	Message     string    `json:"message"`
+	GitExitCode *int      `json:"gitExitCode,omitempty"`
+}
+
+// ReportError writes a failure to stderr. By default the message built from
+// format and args is logged at error level as is. With JSONErrors set, it is emitted as a
+// single-line JSON object carrying code, the message, and git's exit code if
+// err came from a git invocation.
+func ReportError(code ErrorCode, err error, format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+	if !JSONErrors {
+		log.Errorf("%s", message)
+		return
+	}
+
+	out := jsonError{Error: code, Message: strings.TrimSpace(message)}
+	var gitErr *git.Error
+	if errors.As(err, &gitErr) {
+		exitCode := gitErr.ExitCode()
+		out.GitExitCode = &exitCode
+	}
+
+	data, marshalErr := json.Marshal(out)
+	if marshalErr != nil {
+		log.Errorf("%s", message)
+		return
+	}
+	fmt.Fprintln(os.Stderr, string(data))
+}
+
+// ErrorCodeOf classifies err. Failed git invocations are recognized by the
+// messages git prints for the common failure kinds.
+func ErrorCodeOf(err error) ErrorCode {
+	if errors.Is(err, ErrInvalidBranch) {
+		return CodeInvalidBranch
+	}
+	if errors.Is(err, ErrNotGitRepo) {
+		return CodeNotARepo
+	}
+	if errors.Is(err, ErrWorktreeNotFound) {
+		return CodeNotFound
+	}
+	if errors.Is(err, ErrWorktreeExists) {
+		return CodeWorktreeExists
+	}
+	if errors.Is(err, ErrDirtyWorktree) {
+		return CodeDirtyWorktree
+	}
+	if errors.Is(err, ErrUnmergedBranch) {
+		return CodeUnmergedBranch
+	}
+	if errors.Is(err, ErrLocked) {
+		return CodeLocked
+	}
+	if errors.Is(err, ErrForeignState) {
+		return CodeForeignState
+	}
+	if errors.Is(err, ErrProtectedBranch) {
+		return CodeProtectedBranch
+	}
+	if errors.Is(err, ErrWorktreeLocked) {
+		return CodeWorktreeLocked
+	}
+	if errors.Is(err, ErrHookFailed) {
+		return CodeHookFailed
+	}
+	if errors.Is(err, git.ErrTimeout) {
+		return CodeTimeout
+	}
+
+	var gitErr *git.Error
+	if !errors.As(err, &gitErr) {
+		return CodeError
+	}
+
+	stderr := gitErr.Stderr
+	switch {
+	case strings.Contains(stderr, "not a git repository"):
+		return CodeNotARepo
+	case strings.Contains(stderr, "is already checked out at"),
+		strings.Contains(stderr, "is already used by worktree at"):
+		return CodeBranchCheckedOutElsewhere
+	case strings.Contains(stderr, "contains modified or untracked files"):
+		return CodeDirtyWorktree
+	case strings.Contains(stderr, "is not fully merged"):
+		return CodeUnmergedBranch
+	case strings.Contains(stderr, "is not a valid branch name"),
+		strings.Contains(stderr, "not a valid object name"),
+		strings.Contains(stderr, "invalid reference"):
+		return CodeInvalidBranch
+	}
+	return CodeGitFailure
+}
@@ -0,0 +1,116 @@
+package worktree
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sokinpui/wt-go/internal/git"
+	"github.com/sokinpui/wt-go/internal/log"
+)
+
+// FetchAll runs `git fetch --all`, optionally with --prune, and reports each
+// remote-tracking ref that was created, updated or deleted. It is meant to
+// be run once before creating several worktrees for remote branches.
+// Network failures are retried, see execWithRetry.
+func FetchAll(prune bool) error {
+	before, err := remoteRefs()
+	if err != nil {
+		return err
+	}
+
+	args := []string{"fetch", "--all"}
+	if prune {
+		args = append(args, "--prune")
+	}
+	if _, err := execWithRetry(args...); err != nil {
+		return fmt.Errorf("fetching remotes: %w", err)
+	}
+
+	after, err := remoteRefs()
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(before)+len(after))
+	for name := range after {
+		names = append(names, name)
+	}
+	for name := range before {
+		if _, ok := after[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	updated := 0
+	for _, name := range names {
+		oldSHA, hadOld := before[name]
+		newSHA, hasNew := after[name]
+		switch {
+		case !hadOld:
+			log.Infof("ref new: %s %s", name, shortSHA(newSHA))
+		case !hasNew:
+			log.Infof("ref delete: %s", name)
+		case oldSHA != newSHA:
+			log.Infof("ref update: %s %s..%s", name, shortSHA(oldSHA), shortSHA(newSHA))
+		default:
+			continue
+		}
+		updated++
+	}
+	if updated == 0 {
+		log.Infof("All remote-tracking refs are up to date.")
+	}
+	return nil
+}
+
+// fetchBranch updates the remote-tracking ref of branchName from origin, or
+// fetches all remotes if there is no origin. A branch origin does not have is
+// not an error: it is about to be created locally. Network failures are
+// retried, see execWithRetry.
+func fetchBranch(branchName string) error {
+	args := []string{"fetch", "--all"}
+	if _, err := git.Exec("remote", "get-url", defaultRemote); err == nil {
+		args = []string{"fetch", defaultRemote, branchName}
+	}
+
+	log.Infof("fetch: %s", strings.Join(args[1:], " "))
+	_, err := execWithRetry(args...)
+	var gitErr *git.Error
+	if errors.As(err, &gitErr) && strings.Contains(gitErr.Stderr, "couldn't find remote ref") {
+		log.Debugf("%s has no branch '%s'", defaultRemote, branchName)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("fetching branch '%s': %w", branchName, err)
+	}
+	return nil
+}
+
+// remoteRefs maps each remote-tracking ref, e.g. `origin/main`, to the
+// commit it points at.
+func remoteRefs() (map[string]string, error) {
+	output, err := git.Exec("for-each-ref", "--format=%(objectname) %(refname:short)", "refs/remotes")
+	if err != nil {
+		return nil, fmt.Errorf("listing remote-tracking refs: %w", err)
+	}
+
+	refs := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		sha, name, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if ok {
+			refs[name] = sha
+		}
+	}
+	return refs, nil
+}
+
+// shortSHA abbreviates a commit hash for display.
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
@@ -0,0 +1,86 @@
+package worktree
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sokinpui/wt-go/internal/git"
+	"github.com/sokinpui/wt-go/internal/log"
+)
+
+// ErrProtectedBranch is wrapped by errors about refusing to delete or rename
+// a protected branch such as main or master.
+var ErrProtectedBranch = errors.New("branch is protected")
+
+// DiscardBranch prepares branchName to be created from scratch: after
+// confirmation, it removes the branch's worktree, if any, and deletes the
+// branch, reporting the commit it pointed at so it can be recovered with
+// `git branch <name> <commit>`. A branch that does not exist is left alone.
+// Protected branches, the branch of the current worktree, and pinned or
+// locked worktrees are refused.
+func DiscardBranch(branchName string) error {
+	if isProtectedBranch(branchName) {
+		return fmt.Errorf("%w: '%s'", ErrProtectedBranch, branchName)
+	}
+
+	unlock, err := lockRepo()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if !branchExists(branchName) {
+		return nil
+	}
+
+	current, _ := git.Exec("symbolic-ref", "--quiet", "--short", "HEAD")
+	if strings.TrimSpace(current) == branchName {
+		return fmt.Errorf("branch '%s' is checked out in the current worktree; switch away before recreating it", branchName)
+	}
+
+	worktreePath, err := FindWorktreePathForBranch(branchName)
+	if err != nil {
+		return fmt.Errorf("finding worktree for branch '%s': %w", branchName, err)
+	}
+	if worktreePath != "" {
+		if isPinned(worktreePath) {
+			return fmt.Errorf("the worktree of branch '%s' is pinned; run `wtgo unpin %s` first", branchName, branchName)
+		}
+		locked, _, err := worktreeLock(worktreePath)
+		if err != nil {
+			return err
+		}
+		if locked {
+			return fmt.Errorf("%w: '%s'; run `wtgo unlock %s` first", ErrWorktreeLocked, worktreePath, branchName)
+		}
+	}
+
+	sha, err := git.Exec("rev-parse", "--verify", "refs/heads/"+branchName)
+	if err != nil {
+		return fmt.Errorf("resolving branch '%s': %w", branchName, err)
+	}
+	sha = strings.TrimSpace(sha)
+
+	question := fmt.Sprintf("Delete branch '%s' at %s", branchName, shortSHA(sha))
+	if worktreePath != "" {
+		question += fmt.Sprintf(" and its worktree '%s'", worktreePath)
+	}
+	if !Confirm(question + ", then recreate it from scratch?") {
+		return fmt.Errorf("not recreating branch '%s'", branchName)
+	}
+
+	if worktreePath != "" {
+		if _, err := git.Exec("worktree", "remove", "--force", worktreePath); err != nil {
+			return fmt.Errorf("removing worktree '%s': %w", worktreePath, err)
+		}
+		log.Infof("worktree remove: %s", worktreePath)
+	}
+
+	if _, err := git.Exec("branch", "-D", branchName); err != nil {
+		return fmt.Errorf("deleting branch '%s': %w", branchName, err)
+	}
+	log.Infof("branch delete: %s (was %s)", branchName, sha)
+	log.Infof("Recover it with: git branch %s %s", branchName, sha)
+	return nil
+}
@@ -0,0 +1,40 @@
+package worktree
+
+import (
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecreateDiscardedBranchFromBase(t *testing.T) {
+	repo := newTestRepo(t)
+	remote := filepath.Join(filepath.Dir(repo), "remote.git")
+	runGit(t, repo, "init", "--quiet", "--bare", remote)
+	runGit(t, repo, "remote", "add", "origin", remote)
+	exp := filepath.Join(filepath.Dir(repo), "repo.wt", "exp")
+	runGit(t, repo, "worktree", "add", "--quiet", "-b", "exp", exp)
+	runGit(t, exp, "commit", "--quiet", "--allow-empty", "-m", "experiment")
+	runGit(t, exp, "push", "--quiet", "-u", "origin", "exp")
+	t.Chdir(repo)
+	saved := AssumeYes
+	AssumeYes = true
+	t.Cleanup(func() { AssumeYes = saved })
+
+	captureOutput(t, func() {
+		if err := DiscardBranch("exp"); err != nil {
+			t.Fatalf("DiscardBranch: %v", err)
+		}
+		if _, err := CreateWorktree("exp", CreateOptions{Fresh: true, NoSwitch: true}); err != nil {
+			t.Fatalf("CreateWorktree: %v", err)
+		}
+	})
+
+	if got, want := runGit(t, repo, "rev-parse", "exp"), runGit(t, repo, "rev-parse", "main"); got != want {
+		t.Errorf("exp recreated at %s, want main's %s", got, want)
+	}
+	cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "exp@{upstream}")
+	cmd.Dir = repo
+	if output, err := cmd.Output(); err == nil {
+		t.Errorf("exp tracks %s, want no upstream", output)
+	}
+}
@@ -0,0 +1,222 @@
+package worktree
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sokinpui/wt-go/internal/log"
+)
+
+// FindOrphanedDirs returns the directories inside the wtgo collection directory
+// that git does not track as worktrees, e.g. leftovers from a failed create or
+// a worktree whose .git file was deleted by hand.
+// Directories holding worktrees further down, as created for branch names
+// with slashes by the nested layout, are searched rather than reported.
+func FindOrphanedDirs() ([]string, error) {
+	dir, err := collectionDir()
+	if err != nil {
+		return nil, err
+	}
+
+	known, err := worktreePaths()
+	if err != nil {
+		return nil, err
+	}
+
+	orphaned, err := orphanedDirsIn(dir, known)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("reading collection directory '%s': %w", dir, err)
+	}
+	return orphaned, nil
+}
+
+// orphanedDirsIn returns the directories below dir that are neither known
+// worktrees nor hold one. Live checkouts of other repositories, e.g. ones
+// sharing the collection directory, are left out as well.
+func orphanedDirsIn(dir string, known map[string]bool) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var orphaned []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		switch {
+		case known[canonicalPath(path)]:
+		case isLiveCheckout(path):
+			log.Infof("Skipping '%s': it is a checkout of another repository.", path)
+		case holdsWorktree(path, known) || holdsLiveCheckout(path):
+			nested, err := orphanedDirsIn(path, known)
+			if err != nil {
+				return nil, err
+			}
+			orphaned = append(orphaned, nested...)
+		default:
+			orphaned = append(orphaned, path)
+		}
+	}
+	return orphaned, nil
+}
+
+// holdsWorktree reports whether one of the known worktrees lies below dir.
+func holdsWorktree(dir string, known map[string]bool) bool {
+	prefix := canonicalPath(dir) + string(filepath.Separator)
+	for path := range known {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveOrphanedDirs deletes the given directories after verifying that each
+// one still lives inside the collection directory and is still unknown to
+// git, without holding a worktree or a live checkout of any repository. It
+// returns the first error encountered, after attempting all.
+func RemoveOrphanedDirs(dirs []string) error {
+	collection, err := collectionDir()
+	if err != nil {
+		return err
+	}
+	collection = canonicalPath(collection)
+
+	known, err := worktreePaths()
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+	for _, dir := range dirs {
+		canonical := canonicalPath(dir)
+		if !strings.HasPrefix(canonical, collection+string(filepath.Separator)) {
+			err := fmt.Errorf("refusing to delete '%s': not inside the collection directory '%s'", dir, collection)
+			ReportError(ErrorCodeOf(err), err, "Error: %v\n", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if known[canonical] || holdsWorktree(dir, known) {
+			log.Infof("Skipping '%s': it is now a registered worktree.", dir)
+			continue
+		}
+		if isLiveCheckout(dir) || holdsLiveCheckout(dir) {
+			log.Infof("Skipping '%s': it holds a checkout of another repository.", dir)
+			continue
+		}
+
+		if err := os.RemoveAll(dir); err != nil {
+			ReportError(ErrorCodeOf(err), err, "Error removing '%s': %v\n", dir, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		log.Infof("directory remove: %s", dir)
+	}
+	return firstErr
+}
+
+// isLiveCheckout reports whether dir is the root of a checkout whose git
+// directory still exists: a .git directory, or a .git file whose gitdir line
+// points at an existing directory, as for a worktree of any repository.
+func isLiveCheckout(dir string) bool {
+	dotGit := filepath.Join(dir, ".git")
+	info, err := os.Stat(dotGit)
+	if err != nil {
+		return false
+	}
+	if info.IsDir() {
+		return true
+	}
+
+	content, err := os.ReadFile(dotGit)
+	if err != nil {
+		return false
+	}
+	gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(content)), "gitdir:")
+	if !ok {
+		return false
+	}
+	gitDir = filepath.FromSlash(strings.TrimSpace(gitDir))
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(dir, gitDir)
+	}
+	return pathExists(gitDir)
+}
+
+// holdsLiveCheckout reports whether a live checkout, see isLiveCheckout,
+// lies below dir.
+func holdsLiveCheckout(dir string) bool {
+	found := false
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() || path == dir {
+			return nil
+		}
+		if d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if isLiveCheckout(path) {
+			found = true
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return found
+}
+
+// removeEmptyParents deletes the directories left empty by removing the
+// worktree at path, walking up to and including the collection directory.
+// Directories outside the collection directory are never touched, so a
+// worktree created elsewhere leaves its surroundings alone.
+func removeEmptyParents(path string) {
+	collection, err := collectionDir()
+	if err != nil {
+		return
+	}
+	collection = canonicalPath(collection)
+
+	dir := canonicalPath(filepath.Dir(path))
+	for dir == collection || strings.HasPrefix(dir, collection+string(filepath.Separator)) {
+		// Remove fails on a directory that is not empty, which ends the walk.
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		log.Debugf("directory remove: %s", dir)
+		dir = filepath.Dir(dir)
+	}
+}
+
+// worktreePaths returns the canonical paths of all worktrees git knows about.
+func worktreePaths() (map[string]bool, error) {
+	entries, err := listWorktrees()
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make(map[string]bool)
+	for _, entry := range entries {
+		paths[canonicalPath(entry.Path)] = true
+	}
+	return paths, nil
+}
+
+// canonicalPath returns an absolute, symlink-resolved form of path suitable
+// for comparisons. If the path cannot be resolved, it is cleaned instead.
+func canonicalPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		return resolved
+	}
+	return abs
+}
@@ -0,0 +1,27 @@
+package worktree
+
+// FindGoneWorktrees returns the worktrees whose branches track an upstream
+// branch that no longer exists, as after the remote branch of a merged pull
+// request was deleted and pruned. Fetch with pruning first so git knows
+// which upstreams are gone. Worktrees are skipped as for FindMergedWorktrees.
+func FindGoneWorktrees() ([]RemovableWorktree, error) {
+	tracks, err := upstreamTracks()
+	if err != nil {
+		return nil, err
+	}
+	gone := make(map[string]bool)
+	for branch, track := range tracks {
+		if track == "[gone]" {
+			gone[branch] = true
+		}
+	}
+
+	found, err := removableWorktrees(gone, func(string) bool { return false })
+	if err != nil {
+		return nil, err
+	}
+	for i := range found {
+		found[i].UpstreamGone = true
+	}
+	return found, nil
+}
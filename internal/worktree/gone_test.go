@@ -0,0 +1,42 @@
+package worktree
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestRemoveGoneWorktreeSquashMerged removes a branch whose upstream was
+// deleted after a squash merge, so its commits are merged nowhere.
+func TestRemoveGoneWorktreeSquashMerged(t *testing.T) {
+	repo := newTestRepo(t)
+	remote := filepath.Join(filepath.Dir(repo), "remote.git")
+	runGit(t, repo, "init", "--quiet", "--bare", remote)
+	runGit(t, repo, "remote", "add", "origin", remote)
+	feat := filepath.Join(filepath.Dir(repo), "repo.wt", "feat")
+	runGit(t, repo, "worktree", "add", "--quiet", "-b", "feat", feat)
+	runGit(t, feat, "commit", "--quiet", "--allow-empty", "-m", "feat")
+	runGit(t, feat, "push", "--quiet", "-u", "origin", "feat")
+	runGit(t, repo, "push", "--quiet", "origin", "--delete", "feat")
+	runGit(t, repo, "fetch", "--quiet", "--prune")
+	t.Chdir(repo)
+
+	found, err := FindGoneWorktrees()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].Branch != "feat" || !found[0].UpstreamGone {
+		t.Fatalf("FindGoneWorktrees() = %+v, want feat with its upstream gone", found)
+	}
+
+	var removed []string
+	_, stderr := captureOutput(t, func() {
+		removed = RemoveWorktrees(found, false)
+	})
+	if len(removed) != 1 || removed[0] != "feat" {
+		t.Fatalf("RemoveWorktrees removed %q, want [feat]\nstderr:\n%s", removed, stderr)
+	}
+	if _, err := os.Stat(feat); !os.IsNotExist(err) {
+		t.Errorf("worktree %s still exists (stat error %v)", feat, err)
+	}
+}
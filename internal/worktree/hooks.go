@@ -0,0 +1,73 @@
+package worktree
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/sokinpui/wt-go/internal/log"
+)
+
+// ErrHookFailed is wrapped by the error of a hook that exited non-zero.
+var ErrHookFailed = errors.New("hook failed")
+
+// runHook runs the executable `.wtgo/<name>` in the main repository root, if
+// it exists, passing the worktree path and branch as arguments. The hook runs
+// in the repository root, since the worktree may not exist (yet or anymore),
+// and its output is streamed to stderr.
+func runHook(name, worktreePath, branchName string) error {
+	repoRoot, err := mainRepoRoot()
+	if err != nil {
+		return err
+	}
+
+	hookPath := filepath.Join(repoRoot, ".wtgo", name)
+	if _, err := os.Stat(hookPath); os.IsNotExist(err) {
+		return nil
+	}
+	return execHook(name, hookPath, repoRoot, worktreePath, branchName)
+}
+
+// runPostCreateHook runs the post-create hook in the new worktree at
+// worktreePath, passing its path and branch as arguments. The hook is the
+// executable named by WTGO_POST_CREATE, or else `.wtgo/post-create` in the
+// main repository root, if it exists. Its output is streamed to stderr.
+func runPostCreateHook(worktreePath, branchName string) error {
+	hookPath := os.Getenv("WTGO_POST_CREATE")
+	if hookPath == "" {
+		repoRoot, err := mainRepoRoot()
+		if err != nil {
+			return err
+		}
+		hookPath = filepath.Join(repoRoot, ".wtgo", "post-create")
+		if _, err := os.Stat(hookPath); os.IsNotExist(err) {
+			return nil
+		}
+	}
+	return execHook("post-create", hookPath, worktreePath, worktreePath, branchName)
+}
+
+// execHook runs the hook at hookPath in dir with the worktree path and branch
+// as arguments, streaming its output to stderr.
+func execHook(name, hookPath, dir, worktreePath, branchName string) error {
+	info, err := os.Stat(hookPath)
+	if err != nil {
+		return fmt.Errorf("checking %s hook: %w", name, err)
+	}
+	if info.IsDir() || info.Mode()&0111 == 0 {
+		return fmt.Errorf("%s hook '%s' is not executable", name, hookPath)
+	}
+
+	log.Infof("hook run: %s", hookPath)
+	cmd := exec.Command(hookPath, worktreePath, branchName)
+	cmd.Dir = dir
+	// Progress output follows --quiet; error output is always shown.
+	cmd.Stdout = log.Writer(log.LevelInfo)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrHookFailed, hookPath, err)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package worktree
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/sokinpui/wt-go/internal/log"
+)
+
+// ListIgnored makes worktree listings include the branches matched by the
+// ignore file, see ignoredBranchPatterns.
+var ListIgnored bool
+
+func getIgnoreFilePath() (string, error) {
+	return commonDirFile(".wtgoignore")
+}
+
+// ignoredBranchPatterns returns the glob patterns in the `.wtgoignore` file
+// of the git common directory, one per line, of branches that listings
+// leave out. Blank lines and lines starting with `#` are skipped. A missing
+// file ignores nothing.
+func ignoredBranchPatterns() ([]string, error) {
+	ignoreFile, err := getIgnoreFilePath()
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(ignoreFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading ignore file: %w", err)
+	}
+
+	var patterns []string
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			patterns = append(patterns, line)
+		}
+	}
+	return patterns, nil
+}
+
+// isIgnoredBranch reports whether branch matches one of patterns. As in
+// path.Match, `*` does not match `/`, so `release/*` matches `release/1.0`
+// but `release*` does not. Malformed patterns match nothing.
+func isIgnoredBranch(branch string, patterns []string) bool {
+	for _, pattern := range patterns {
+		matched, err := path.Match(pattern, branch)
+		if err != nil {
+			log.Debugf("ignoring malformed .wtgoignore pattern '%s': %v", pattern, err)
+			continue
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
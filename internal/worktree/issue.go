@@ -0,0 +1,124 @@
+package worktree
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/sokinpui/wt-go/internal/git"
+)
+
+// defaultBranchTemplate is used when wtgo.branchTemplate is unset.
+const defaultBranchTemplate = "{issue}/{name}"
+
+// BranchNameForIssue builds the branch name for a worktree named name that
+// belongs to issue, using the wtgo.branchTemplate config (default
+// `{issue}/{name}`). The result is validated and normalized with
+// `git check-ref-format --branch`.
+func BranchNameForIssue(name, issue string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("branch name cannot be empty")
+	}
+	if issue == "" || strings.ContainsAny(issue, " \t\r\n") {
+		return "", fmt.Errorf("invalid issue id '%s'", issue)
+	}
+
+	template := configValue("wtgo.branchTemplate")
+	if template == "" {
+		template = defaultBranchTemplate
+	}
+	if !strings.Contains(template, "{name}") {
+		return "", fmt.Errorf("wtgo.branchTemplate '%s' must contain the {name} placeholder", template)
+	}
+
+	branchName := strings.NewReplacer("{issue}", issue, "{name}", name).Replace(template)
+	output, err := git.Exec("check-ref-format", "--branch", branchName)
+	if err != nil {
+		return "", fmt.Errorf("'%s' is not a valid branch name: %w", branchName, err)
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// RecordIssue associates branchName with issue, so ListWorktrees can show it.
+// Associations are stored as `<branch>\t<issue>` lines in the wt.issues file
+// in the git common directory.
+func RecordIssue(branchName, issue string) error {
+	unlock, err := lockRepo()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	issues, err := branchIssues()
+	if err != nil {
+		return err
+	}
+	if issues[branchName] == issue {
+		return nil
+	}
+	issues[branchName] = issue
+	return writeBranchIssues(issues)
+}
+
+// forgetIssue drops the issue association of branchName, if any. The caller
+// must hold the repository lock.
+func forgetIssue(branchName string) error {
+	issues, err := branchIssues()
+	if err != nil {
+		return err
+	}
+	if _, ok := issues[branchName]; !ok {
+		return nil
+	}
+	delete(issues, branchName)
+	return writeBranchIssues(issues)
+}
+
+// branchIssues reads the branch to issue associations. A missing file means
+// no branch has an issue.
+func branchIssues() (map[string]string, error) {
+	issuesFile, err := getIssuesFilePath()
+	if err != nil {
+		return nil, err
+	}
+
+	issues := make(map[string]string)
+	content, err := os.ReadFile(issuesFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return issues, nil
+		}
+		return nil, fmt.Errorf("reading issues file: %w", err)
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		branch, issue, ok := strings.Cut(strings.TrimSpace(line), "\t")
+		if ok && branch != "" && issue != "" {
+			issues[branch] = issue
+		}
+	}
+	return issues, nil
+}
+
+func writeBranchIssues(issues map[string]string) error {
+	issuesFile, err := getIssuesFilePath()
+	if err != nil {
+		return err
+	}
+
+	branches := make([]string, 0, len(issues))
+	for branch := range issues {
+		branches = append(branches, branch)
+	}
+	sort.Strings(branches)
+
+	var content strings.Builder
+	for _, branch := range branches {
+		fmt.Fprintf(&content, "%s\t%s\n", branch, issues[branch])
+	}
+	if err := writeFileAtomic(issuesFile, []byte(content.String()), 0644); err != nil {
+		return fmt.Errorf("writing issues file: %w", err)
+	}
+	return nil
+}
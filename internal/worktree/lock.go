@@ -0,0 +1,48 @@
+package worktree
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// lockTimeout is how long an invocation waits for another wtgo process to
+// release the repository lock before giving up.
+const lockTimeout = 5 * time.Second
+
+// lockPollInterval is the delay between attempts to acquire the lock.
+const lockPollInterval = 50 * time.Millisecond
+
+// ErrLocked is returned when the repository lock could not be acquired in time.
+var ErrLocked = errors.New("another wtgo operation is in progress; try again shortly")
+
+// lockRepo acquires an advisory lock on the wt.lock file in the git common
+// directory. It serializes state mutations and worktree creation/removal
+// across concurrent wtgo invocations. The returned function releases the
+// lock; calls after the first do nothing, so a caller deferring it can still
+// release early, e.g. before running a hook that may itself invoke wtgo.
+func lockRepo() (func(), error) {
+	lockFile, err := getLockFilePath()
+	if err != nil {
+		return nil, fmt.Errorf("getting lock file path: %w", err)
+	}
+
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		unlock, err := tryLock(lockFile)
+		if err == nil {
+			return sync.OnceFunc(unlock), nil
+		}
+		if !errors.Is(err, errLockHeld) {
+			return nil, fmt.Errorf("acquiring lock %s: %w", lockFile, err)
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%w (if no wtgo is running, delete %s)", ErrLocked, lockFile)
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// errLockHeld signals that another process currently holds the lock.
+var errLockHeld = errors.New("lock held")
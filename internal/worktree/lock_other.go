@@ -0,0 +1,90 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package worktree
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// staleLockAge is how old a lock file must be to be broken even though its
+// owner cannot be shown to be gone. It is far longer than any wtgo
+// operation holds the lock.
+const staleLockAge = 10 * time.Minute
+
+// tryLock makes a single attempt to create the lock file exclusively.
+// On platforms without flock, the lock file's existence is the lock, so it
+// is removed again on release. The file holds the owner's PID, so a lock
+// left behind by a crashed wtgo can be told apart and broken.
+func tryLock(path string) (func(), error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			breakStaleLock(path)
+			return nil, errLockHeld
+		}
+		return nil, err
+	}
+	fmt.Fprintf(f, "%d\n", os.Getpid())
+
+	return func() {
+		f.Close()
+		os.Remove(path)
+	}, nil
+}
+
+// breakStaleLock removes the lock file at path if it is stale. The file is
+// renamed aside first and checked again, so a lock another process took in
+// the meantime is put back rather than removed. The caller retries.
+func breakStaleLock(path string) {
+	if !lockIsStale(path) {
+		return
+	}
+	aside := fmt.Sprintf("%s.stale-%d", path, os.Getpid())
+	if err := os.Rename(path, aside); err != nil {
+		return
+	}
+	if !lockIsStale(aside) {
+		os.Rename(aside, path)
+		return
+	}
+	os.Remove(aside)
+}
+
+// lockIsStale reports whether the lock file at path was left behind: its
+// owner is no longer running, or it is older than staleLockAge.
+func lockIsStale(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	if time.Since(info.ModTime()) > staleLockAge {
+		return true
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil {
+		// The owner may not have written its PID yet.
+		return false
+	}
+	return !processExists(pid)
+}
+
+// processExists reports whether a process with pid may be running. On
+// Windows, os.FindProcess fails for a process that no longer exists;
+// elsewhere it always succeeds, leaving only staleLockAge to break a lock.
+func processExists(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
@@ -0,0 +1,64 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package worktree
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestTryLockBreaksStaleLocks(t *testing.T) {
+	// A PID that no longer exists: that of a process that has exited.
+	child := exec.Command(os.Args[0], "-test.run=^$")
+	if err := child.Run(); err != nil {
+		t.Fatal(err)
+	}
+	deadPID := child.Process.Pid
+
+	tests := []struct {
+		name      string
+		content   string
+		age       time.Duration
+		wantStale bool
+	}{
+		{"live owner", fmt.Sprintf("%d\n", os.Getpid()), 0, false},
+		{"owner still writing", "", 0, false},
+		{"old", fmt.Sprintf("%d\n", os.Getpid()), 2 * staleLockAge, true},
+		{"dead owner", fmt.Sprintf("%d\n", deadPID), 0, runtime.GOOS == "windows"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "wt.lock")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if tt.age > 0 {
+				old := time.Now().Add(-tt.age)
+				if err := os.Chtimes(path, old, old); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// The first attempt finds the lock held and breaks it if stale,
+			// so the retry gets it.
+			if _, err := tryLock(path); !errors.Is(err, errLockHeld) {
+				t.Fatalf("first tryLock = %v, want errLockHeld", err)
+			}
+			unlock, err := tryLock(path)
+			if tt.wantStale {
+				if err != nil {
+					t.Fatalf("the stale lock was not broken: %v", err)
+				}
+				unlock()
+			} else if !errors.Is(err, errLockHeld) {
+				t.Fatalf("a held lock was broken: tryLock = %v", err)
+			}
+		})
+	}
+}
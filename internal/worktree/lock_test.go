@@ -0,0 +1,125 @@
+package worktree
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestStateHelperProcess is not a real test: TestConcurrentStateWrites runs
+// the test binary as separate wtgo-like processes that enter this function
+// with WTGO_TEST_STATE_OP set.
+func TestStateHelperProcess(t *testing.T) {
+	arg := os.Getenv("WTGO_TEST_STATE_ARG")
+	switch os.Getenv("WTGO_TEST_STATE_OP") {
+	case "":
+		return
+	case "push":
+		// The read-modify-write saveCurrentWorktreeState does, with an entry
+		// per process so lost updates show up as missing entries.
+		unlock, err := lockRepo()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		history, err := readHistory()
+		if err == nil {
+			err = pushHistory(append(history, arg))
+		}
+		unlock()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case "pin":
+		if err := PinWorktree(arg); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	os.Exit(0)
+}
+
+func TestConcurrentStateWrites(t *testing.T) {
+	repo := newTestRepo(t)
+	const n = 8
+
+	var worktrees []string
+	for i := 0; i < n; i++ {
+		branch := fmt.Sprintf("b%d", i)
+		path := filepath.Join(filepath.Dir(repo), "repo.wt", branch)
+		runGit(t, repo, "worktree", "add", "--quiet", "-b", branch, path)
+		worktrees = append(worktrees, path)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*n)
+	start := func(op, arg string) {
+		defer wg.Done()
+		cmd := exec.Command(os.Args[0], "-test.run=^TestStateHelperProcess$")
+		cmd.Dir = repo
+		cmd.Env = append(os.Environ(),
+			"WTGO_TEST_STATE_OP="+op,
+			"WTGO_TEST_STATE_ARG="+arg,
+		)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			errs <- fmt.Errorf("%s %s: %v\n%s", op, arg, err, output)
+		}
+	}
+	for i := 0; i < n; i++ {
+		wg.Add(2)
+		go start("push", fmt.Sprintf("/entry/%d", i))
+		go start("pin", fmt.Sprintf("b%d", i))
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	t.Chdir(repo)
+	history, err := readHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != n+1 {
+		t.Errorf("history has %d entries, want %d: %q", len(history), n+1, history)
+	}
+	if len(history) > 0 && history[0] != repo {
+		t.Errorf("history[0] = %q, want %q", history[0], repo)
+	}
+	seen := make(map[string]int)
+	for _, entry := range history {
+		seen[entry]++
+	}
+	for i := 0; i < n; i++ {
+		if entry := fmt.Sprintf("/entry/%d", i); seen[entry] != 1 {
+			t.Errorf("history has %q %d times, want once", entry, seen[entry])
+		}
+	}
+
+	pinned, err := pinnedPaths()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range worktrees {
+		if !pinned[canonicalPath(path)] {
+			t.Errorf("%s is not pinned", path)
+		}
+	}
+	if len(pinned) != n {
+		t.Errorf("%d worktrees pinned, want %d", len(pinned), n)
+	}
+
+	leftovers, err := filepath.Glob(filepath.Join(repo, ".git", ".wt.*.tmp-*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leftovers) > 0 {
+		t.Errorf("temporary files left behind: %s", strings.Join(leftovers, ", "))
+	}
+}
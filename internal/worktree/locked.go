@@ -0,0 +1,89 @@
+package worktree
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sokinpui/wt-go/internal/git"
+	"github.com/sokinpui/wt-go/internal/log"
+)
+
+// ErrWorktreeLocked is wrapped by errors about operations refused because
+// git has locked the worktree, e.g. one on a removable drive.
+var ErrWorktreeLocked = errors.New("worktree is locked")
+
+// LockWorktree locks the worktree of branchName with `git worktree lock`,
+// so git refuses to remove, move or prune it. reason may be empty.
+func LockWorktree(branchName, reason string) error {
+	worktreePath, err := worktreePathOrError(branchName)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"worktree", "lock"}
+	if reason != "" {
+		args = append(args, "--reason", reason)
+	}
+	args = append(args, worktreePath)
+	if _, err := git.Exec(args...); err != nil {
+		return fmt.Errorf("locking worktree '%s': %w", worktreePath, err)
+	}
+	log.Infof("worktree lock: %s", worktreePath)
+	return nil
+}
+
+// UnlockWorktree removes the git lock from the worktree of branchName.
+func UnlockWorktree(branchName string) error {
+	worktreePath, err := worktreePathOrError(branchName)
+	if err != nil {
+		return err
+	}
+
+	if _, err := git.Exec("worktree", "unlock", worktreePath); err != nil {
+		return fmt.Errorf("unlocking worktree '%s': %w", worktreePath, err)
+	}
+	log.Infof("worktree unlock: %s", worktreePath)
+	return nil
+}
+
+func worktreePathOrError(branchName string) (string, error) {
+	if branchName == "" {
+		return "", fmt.Errorf("branch name cannot be empty")
+	}
+	worktreePath, err := FindWorktreePathForBranch(branchName)
+	if err != nil {
+		return "", fmt.Errorf("finding worktree for branch '%s': %w", branchName, err)
+	}
+	if worktreePath == "" {
+		return "", MissingWorktreeError(branchName)
+	}
+	return worktreePath, nil
+}
+
+// worktreeLock reports whether git has locked the worktree at path, and the
+// lock reason if one was given.
+func worktreeLock(path string) (bool, string, error) {
+	entries, err := listWorktrees()
+	if err != nil {
+		return false, "", err
+	}
+
+	target := canonicalPath(path)
+	for _, entry := range entries {
+		if entry.Locked && canonicalPath(entry.Path) == target {
+			return true, entry.LockReason, nil
+		}
+	}
+	return false, "", nil
+}
+
+// parseLockedLine recognizes a porcelain `locked` line, which carries the
+// lock reason if one was given, and returns that reason.
+func parseLockedLine(line string) (string, bool) {
+	if line == "locked" {
+		return "", true
+	}
+	reason, ok := strings.CutPrefix(line, "locked ")
+	return reason, ok
+}
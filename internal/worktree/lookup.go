@@ -0,0 +1,41 @@
+package worktree
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// WorktreeLookup is the JSON form of a PrintWorktreeLookup result.
+type WorktreeLookup struct {
+	Branch string `json:"branch"`
+	Path   string `json:"path"`
+	Exists bool   `json:"exists"`
+}
+
+// PrintWorktreeLookup prints the path of the worktree of branchName with
+// PrintPath, or nothing if it has none. With JSONOutput set, it prints a
+// WorktreeLookup instead, whose Exists tells the two cases apart. A branch
+// without a worktree is not an error.
+func PrintWorktreeLookup(branchName string) error {
+	if branchName == "" {
+		return ErrEmptyBranch
+	}
+
+	path, err := FindWorktreePathForBranch(branchName)
+	if err != nil {
+		return err
+	}
+
+	if JSONOutput {
+		data, err := json.Marshal(WorktreeLookup{Branch: branchName, Path: path, Exists: path != ""})
+		if err != nil {
+			return fmt.Errorf("encoding worktree lookup: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	if path != "" {
+		PrintPath(path)
+	}
+	return nil
+}
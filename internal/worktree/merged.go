@@ -0,0 +1,133 @@
+package worktree
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/sokinpui/wt-go/internal/git"
+	"github.com/sokinpui/wt-go/internal/log"
+)
+
+// RemovableWorktree is a worktree selected for bulk removal together with
+// its branch, such as one whose branch is merged into a base.
+type RemovableWorktree struct {
+	Path   string
+	Branch string
+	// MergedInto is the ref the branch was found merged into, if that is
+	// why it was selected. Its branch is then deleted if it still is.
+	MergedInto string
+	// UpstreamGone is set if the branch was selected because its upstream
+	// branch was deleted. Its branch is then deleted even if unmerged, as
+	// after a squash merge.
+	UpstreamGone bool
+}
+
+// FindMergedWorktrees returns the worktrees whose branches are fully merged
+// into base, per `git branch --merged`, together with the base used. An
+// empty base means the default branch. The protected branches, the base itself,
+// the current worktree and pinned or locked worktrees are skipped, each with
+// a message saying why.
+func FindMergedWorktrees(base string) (string, []RemovableWorktree, error) {
+	if base == "" {
+		var ref string
+		base, ref = defaultBranch()
+		if ref == "" {
+			return "", nil, fmt.Errorf("cannot determine the default branch; name the base explicitly")
+		}
+		base = ref
+	}
+	if _, err := git.Exec("rev-parse", "--verify", "--quiet", base+"^{commit}"); err != nil {
+		return "", nil, fmt.Errorf("base '%s' does not resolve to a commit", base)
+	}
+
+	output, err := git.Exec("branch", "--merged", base, "--format=%(refname:short)")
+	if err != nil {
+		return "", nil, fmt.Errorf("listing branches merged into '%s': %w", base, err)
+	}
+	merged := make(map[string]bool)
+	for _, branch := range strings.Split(output, "\n") {
+		if branch = strings.TrimSpace(branch); branch != "" {
+			merged[branch] = true
+		}
+	}
+
+	found, err := removableWorktrees(merged, func(branch string) bool {
+		return branch == base || defaultRemote+"/"+branch == base
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	for i := range found {
+		found[i].MergedInto = base
+	}
+	return base, found, nil
+}
+
+// removableWorktrees returns the worktrees of the selected branches, skipping
+// the protected branches, those protected reports true for, the current worktree
+// and pinned or locked worktrees, each with a message saying why.
+func removableWorktrees(selected map[string]bool, protected func(branch string) bool) ([]RemovableWorktree, error) {
+	entries, err := listWorktrees()
+	if err != nil {
+		return nil, err
+	}
+
+	var currentTop string
+	if top, err := git.Exec("rev-parse", "--show-toplevel"); err == nil {
+		currentTop = canonicalPath(filepath.FromSlash(strings.TrimSpace(top)))
+	}
+
+	defaultProtected := protectedBranches()
+	var found []RemovableWorktree
+	for _, entry := range entries {
+		if entry.Bare || entry.Detached || !selected[entry.Branch] {
+			continue
+		}
+		switch {
+		case defaultProtected[entry.Branch] || protected(entry.Branch):
+			log.Infof("Skipping branch '%s': protected branch.", entry.Branch)
+		case canonicalPath(entry.Path) == currentTop:
+			log.Infof("Skipping branch '%s': current worktree.", entry.Branch)
+		case isPinned(entry.Path):
+			log.Infof("Skipping branch '%s': pinned worktree.", entry.Branch)
+		case entry.Locked:
+			log.Infof("Skipping branch '%s': locked worktree.", entry.Branch)
+		default:
+			found = append(found, RemovableWorktree{Path: entry.Path, Branch: entry.Branch})
+		}
+	}
+	return found, nil
+}
+
+// IsDirty reports whether the worktree has uncommitted changes or untracked
+// files, which make its removal fail without --force. A worktree whose status
+// cannot be read counts as clean; the removal itself reports the problem.
+func (wt RemovableWorktree) IsDirty() bool {
+	status, err := git.ExecIn(wt.Path, "status", "--porcelain")
+	if err != nil {
+		log.Debugf("could not check '%s' for changes: %v", wt.Path, err)
+		return false
+	}
+	return strings.TrimSpace(status) != ""
+}
+
+// RemoveWorktrees removes the given worktrees and their branches as
+// RemoveWorktreeAndBranch does, reporting failures and going on with the
+// rest, and returns the branches that are gone afterwards. A branch selected
+// for being merged into a ref is deleted once it is checked to still be, not
+// by what `git branch -d` compares against, and one selected for its gone
+// upstream is deleted regardless.
+func RemoveWorktrees(worktrees []RemovableWorktree, force bool) []string {
+	var removed []string
+	for _, wt := range worktrees {
+		opts := removeOptions{force: force, mergedInto: wt.MergedInto, unmerged: wt.UpstreamGone}
+		if err := removeWorktreeAndBranch(wt.Branch, opts); err != nil {
+			ReportError(ErrorCodeOf(err), err, "Error: %v\n", err)
+		}
+		if !branchExists(wt.Branch) {
+			removed = append(removed, wt.Branch)
+		}
+	}
+	return removed
+}
@@ -0,0 +1,108 @@
+package worktree
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestRemoveWorktreesMergedIntoOtherRef removes a branch merged into dev but
+// not into main, its upstream-less fallback for `git branch -d`.
+func TestRemoveWorktreesMergedIntoOtherRef(t *testing.T) {
+	repo := newTestRepo(t)
+	t.Chdir(repo)
+	runGit(t, repo, "branch", "dev")
+	feat := filepath.Join(filepath.Dir(repo), "repo.wt", "feat")
+	runGit(t, repo, "worktree", "add", "--quiet", "-b", "feat", feat)
+	runGit(t, feat, "commit", "--quiet", "--allow-empty", "-m", "feat")
+	runGit(t, repo, "update-ref", "refs/heads/dev", "refs/heads/feat")
+
+	base, found, err := FindMergedWorktrees("dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].Branch != "feat" || found[0].MergedInto != base {
+		t.Fatalf("FindMergedWorktrees(dev) = %q, %+v; want feat merged into it", base, found)
+	}
+
+	var removed []string
+	_, stderr := captureOutput(t, func() {
+		removed = RemoveWorktrees(found, false)
+	})
+	if len(removed) != 1 || removed[0] != "feat" {
+		t.Fatalf("RemoveWorktrees removed %q, want [feat]\nstderr:\n%s", removed, stderr)
+	}
+	if branchExists("feat") {
+		t.Error("branch feat still exists")
+	}
+	if _, err := os.Stat(feat); !os.IsNotExist(err) {
+		t.Errorf("worktree %s still exists (stat error %v)", feat, err)
+	}
+}
+
+// TestRemoveMergedWorktreesFromFeatureWorktree removes a branch merged into
+// the default branch while the current worktree's HEAD does not contain it.
+func TestRemoveMergedWorktreesFromFeatureWorktree(t *testing.T) {
+	repo := newTestRepo(t)
+	wtDir := filepath.Join(filepath.Dir(repo), "repo.wt")
+	feature := filepath.Join(wtDir, "feature")
+	done := filepath.Join(wtDir, "done")
+	runGit(t, repo, "worktree", "add", "--quiet", "-b", "feature", feature)
+	runGit(t, repo, "worktree", "add", "--quiet", "-b", "done", done)
+	runGit(t, done, "commit", "--quiet", "--allow-empty", "-m", "done")
+	runGit(t, repo, "merge", "--quiet", "--ff-only", "done")
+	t.Chdir(feature)
+
+	base, found, err := FindMergedWorktrees("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if base != "main" || len(found) != 1 || found[0].Branch != "done" {
+		t.Fatalf("FindMergedWorktrees() = %q, %+v; want done merged into main", base, found)
+	}
+
+	var removed []string
+	_, stderr := captureOutput(t, func() {
+		removed = RemoveWorktrees(found, false)
+	})
+	if len(removed) != 1 || removed[0] != "done" {
+		t.Fatalf("RemoveWorktrees removed %q, want [done]\nstderr:\n%s", removed, stderr)
+	}
+	if branchExists("done") {
+		t.Error("branch done still exists")
+	}
+}
+
+// TestRemoveUnmergedBranchTouchesNothing checks an unmerged branch is
+// refused before its worktree is removed along with its ignored files.
+func TestRemoveUnmergedBranchTouchesNothing(t *testing.T) {
+	repo := newTestRepo(t)
+	t.Chdir(repo)
+	feat := filepath.Join(filepath.Dir(repo), "repo.wt", "feat")
+	runGit(t, repo, "worktree", "add", "--quiet", "-b", "feat", feat)
+	runGit(t, feat, "commit", "--quiet", "--allow-empty", "-m", "feat")
+	if err := os.WriteFile(filepath.Join(repo, ".git", "info", "exclude"), []byte("*.log\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(feat, "build.log"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := RemoveWorktreeAndBranch("feat", false, false)
+	if !errors.Is(err, ErrUnmergedBranch) {
+		t.Fatalf("RemoveWorktreeAndBranch = %v, want ErrUnmergedBranch", err)
+	}
+	if ErrorCodeOf(err) != CodeUnmergedBranch {
+		t.Errorf("ErrorCodeOf = %q, want %q", ErrorCodeOf(err), CodeUnmergedBranch)
+	}
+	if _, err := os.Stat(filepath.Join(feat, "build.log")); err != nil {
+		t.Errorf("ignored file was removed: %v", err)
+	}
+	if !branchExists("feat") {
+		t.Error("branch feat was deleted")
+	}
+	if _, err := os.Stat(filepath.Join(repo, ".git", "wt.last-removed")); !os.IsNotExist(err) {
+		t.Errorf("a removal was recorded (stat error %v)", err)
+	}
+}
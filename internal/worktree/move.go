@@ -0,0 +1,141 @@
+package worktree
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/sokinpui/wt-go/internal/git"
+	"github.com/sokinpui/wt-go/internal/log"
+)
+
+// MoveWorktree moves the worktree of branchName to newPath with
+// `git worktree move` and returns the new absolute path. newPath must not
+// exist yet; its parent directories are created as needed. A pin on the
+// worktree and its entries in the worktree history move along with it.
+func MoveWorktree(branchName, newPath string) (string, error) {
+	unlock, err := lockRepo()
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	oldPath, err := worktreePathOrError(branchName)
+	if err != nil {
+		return "", err
+	}
+
+	newPath, err = filepath.Abs(newPath)
+	if err != nil {
+		return "", fmt.Errorf("resolving destination '%s': %w", newPath, err)
+	}
+	if pathExists(newPath) {
+		return "", fmt.Errorf("destination '%s' already exists", newPath)
+	}
+	if err := ensureParentDir(newPath); err != nil {
+		return "", err
+	}
+
+	if err := moveWorktreeDir(oldPath, newPath); err != nil {
+		return "", err
+	}
+	return newPath, nil
+}
+
+// RenameWorktree renames branch oldName to newName with `git branch -m` and
+// returns the path of its worktree. A worktree at the location wtgo picks for
+// oldName moves to the one for newName; a worktree elsewhere stays put. The
+// protected branches cannot be renamed, and neither a branch nor a directory may
+// exist under the new name yet.
+func RenameWorktree(oldName, newName string) (string, error) {
+	if oldName == "" || newName == "" {
+		return "", fmt.Errorf("%w: branch names cannot be empty", ErrInvalidBranch)
+	}
+	if isProtectedBranch(oldName) {
+		return "", fmt.Errorf("%w: '%s' cannot be renamed", ErrProtectedBranch, oldName)
+	}
+
+	unlock, err := lockRepo()
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	oldPath, err := worktreePathOrError(oldName)
+	if err != nil {
+		return "", err
+	}
+	if branchExists(newName) {
+		return "", fmt.Errorf("%w: branch '%s' already exists", ErrInvalidBranch, newName)
+	}
+
+	newPath := oldPath
+	if defaultPath, err := newWorktreePathForBranch(oldName); err == nil && canonicalPath(defaultPath) == canonicalPath(oldPath) {
+		if newPath, err = newWorktreePathForBranch(newName); err != nil {
+			return "", err
+		}
+		if pathExists(newPath) {
+			return "", fmt.Errorf("destination '%s' already exists", newPath)
+		}
+		if err := ensureParentDir(newPath); err != nil {
+			return "", err
+		}
+	} else {
+		log.Infof("worktree '%s' is not in the default location for '%s'; leaving it in place", oldPath, oldName)
+	}
+
+	if _, err := git.Exec("branch", "-m", oldName, newName); err != nil {
+		return "", fmt.Errorf("renaming branch '%s': %w", oldName, err)
+	}
+	log.Infof("branch rename: %s -> %s", oldName, newName)
+
+	if newPath != oldPath {
+		if err := moveWorktreeDir(oldPath, newPath); err != nil {
+			// Put the branch name back so the two stay in step.
+			if _, undoErr := git.Exec("branch", "-m", newName, oldName); undoErr != nil {
+				log.Warnf("could not rename branch '%s' back to '%s': %v", newName, oldName, undoErr)
+			}
+			return "", err
+		}
+	}
+
+	issues, err := branchIssues()
+	if err == nil {
+		if issue, ok := issues[oldName]; ok {
+			delete(issues, oldName)
+			issues[newName] = issue
+			err = writeBranchIssues(issues)
+		}
+	}
+	if err != nil {
+		log.Warnf("could not move the issue of branch '%s': %v", oldName, err)
+	}
+	return newPath, nil
+}
+
+// moveWorktreeDir moves the worktree at oldPath to newPath with
+// `git worktree move`, carrying its pin and its entries in the worktree
+// history along. The caller must hold the repository lock.
+func moveWorktreeDir(oldPath, newPath string) error {
+	wasPinned := isPinned(oldPath)
+	if _, err := git.Exec("worktree", "move", oldPath, newPath); err != nil {
+		return fmt.Errorf("moving worktree '%s': %w", oldPath, err)
+	}
+	log.Infof("worktree move: %s -> %s", oldPath, newPath)
+
+	if wasPinned {
+		pinned, err := pinnedPaths()
+		if err == nil {
+			delete(pinned, canonicalPath(oldPath))
+			pinned[canonicalPath(newPath)] = true
+			err = writePinnedPaths(pinned)
+		}
+		if err != nil {
+			log.Warnf("could not move the pin of '%s': %v", oldPath, err)
+		}
+	}
+
+	if err := moveHistoryEntries(oldPath, newPath); err != nil {
+		log.Warnf("could not update the worktree history: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package worktree
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sokinpui/wt-go/internal/log"
+)
+
+// OutputOptions controls how resolved worktree paths are handed to the caller.
+type OutputOptions struct {
+	// CdFile, when set, receives the resolved path in addition to stdout.
+	// This lets integrations that cannot capture stdout read the path from a file.
+	CdFile string
+
+	// Print0 terminates printed paths, and the branch names of listings, with
+	// a NUL byte, for `xargs -0` and paths containing spaces or newlines.
+	Print0 bool
+
+	// Relative prints paths relative to the current working directory. The
+	// cd file still receives the absolute path.
+	Relative bool
+
+	// RelativeTo is the directory Relative paths are relative to instead of
+	// the current working directory, as when -C changed it.
+	RelativeTo string
+}
+
+// Output holds the output options configured by the CLI.
+var Output OutputOptions
+
+// JSONOutput makes listings print JSON on stdout instead of human-readable text.
+var JSONOutput bool
+
+// ListPaths makes worktree listings print each branch and its worktree path,
+// separated by a tab, instead of the branch alone.
+var ListPaths bool
+
+// ListTracking makes the human-readable worktree listing annotate each
+// branch with how it compares to its upstream, e.g. "[ahead 1, behind 2]".
+var ListTracking bool
+
+// ListAge sorts worktree listings by the date of each branch's last commit,
+// oldest first, and makes the human-readable listing show that date, e.g.
+// "(committed 3 weeks ago)".
+var ListAge bool
+
+// PrintPath writes a resolved worktree path to stdout and, if configured,
+// to the cd file. By default the path has no trailing newline, so wrappers
+// can use it verbatim; with Print0 it is followed by a NUL byte. The cd file
+// always holds the bare path.
+func PrintPath(path string) {
+	printed := path
+	if Output.Relative {
+		printed = relativePath(path)
+	}
+	if Output.Print0 {
+		fmt.Print(printed + "\x00")
+	} else {
+		fmt.Print(printed)
+	}
+
+	if Output.CdFile != "" {
+		if err := writeFileAtomic(Output.CdFile, []byte(path), 0644); err != nil {
+			log.Warnf("could not write path to '%s': %v", Output.CdFile, err)
+		}
+	}
+}
+
+// relativePath returns path relative to Output.RelativeTo or the current
+// working directory, or path itself if no relative form exists, e.g. on
+// another Windows drive.
+func relativePath(path string) string {
+	wd := Output.RelativeTo
+	if wd == "" {
+		var err error
+		if wd, err = os.Getwd(); err != nil {
+			return path
+		}
+	}
+	rel, err := filepath.Rel(wd, path)
+	if err != nil {
+		return path
+	}
+	return rel
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory as
+// name and renames it into place, so readers never observe a partial write.
+func writeFileAtomic(name string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+
+	if err := os.Rename(tmpName, name); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package worktree
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/sokinpui/wt-go/internal/log"
+)
+
+// ErrNothingSelected is returned by PickWorktree when the user makes no valid
+// choice.
+var ErrNothingSelected = errors.New("no worktree selected")
+
+// PickWorktree shows a numbered menu of the worktrees with a branch on
+// stderr, reads the choice from the controlling terminal, and returns the
+// chosen worktree's path. Stdin and stdout stay free, so it works inside
+// `cd "$(wtgo -i)"`. Unless the choice is the current worktree, the current
+// directory is recorded for `wtgo -`.
+func PickWorktree() (string, error) {
+	entries, err := listWorktrees()
+	if err != nil {
+		return "", err
+	}
+
+	var choices []worktreeEntry
+	width := 0
+	for _, entry := range entries {
+		if entry.Branch == "" {
+			continue
+		}
+		choices = append(choices, entry)
+		width = max(width, len(entry.Branch))
+	}
+	if len(choices) == 0 {
+		return "", fmt.Errorf("no worktrees with a branch to choose from")
+	}
+
+	tty, err := openTerminal()
+	if err != nil {
+		return "", fmt.Errorf("opening the terminal for the worktree menu: %w", err)
+	}
+	defer tty.Close()
+
+	for i, entry := range choices {
+		fmt.Fprintf(os.Stderr, "%3d) %-*s  %s\n", i+1, width, entry.Branch, entry.Path)
+	}
+	fmt.Fprintf(os.Stderr, "Select a worktree [1-%d]: ", len(choices))
+
+	answer, err := bufio.NewReader(tty).ReadString('\n')
+	if err != nil && strings.TrimSpace(answer) == "" {
+		fmt.Fprintln(os.Stderr)
+		return "", ErrNothingSelected
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(answer))
+	if err != nil || n < 1 || n > len(choices) {
+		return "", fmt.Errorf("%w: '%s' is not a number from 1 to %d", ErrNothingSelected, strings.TrimSpace(answer), len(choices))
+	}
+	path := choices[n-1].Path
+
+	unlock, err := lockRepo()
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	if wd, err := os.Getwd(); err != nil {
+		log.Warnf("could not get current working directory: %v", err)
+	} else if canonicalPath(wd) != canonicalPath(path) {
+		if err := saveCurrentWorktreeState(); err != nil {
+			log.Warnf("could not save current worktree state: %v", err)
+		}
+	}
+	return path, nil
+}
+
+// openTerminal opens the controlling terminal for reading.
+func openTerminal() (*os.File, error) {
+	if runtime.GOOS == "windows" {
+		return os.Open("CONIN$")
+	}
+	return os.Open("/dev/tty")
+}
+
+// DeselectWorktrees shows worktrees as a numbered menu on stderr and reads
+// from the controlling terminal the numbers of those to leave out, separated
+// by spaces or commas. It returns the rest, which is all of them for an
+// empty answer, or ErrNothingSelected if the user answers "q".
+func DeselectWorktrees(worktrees []RemovableWorktree) ([]RemovableWorktree, error) {
+	tty, err := openTerminal()
+	if err != nil {
+		return nil, fmt.Errorf("opening the terminal for the worktree menu: %w; pass --yes to skip it", err)
+	}
+	defer tty.Close()
+
+	width := 0
+	for _, wt := range worktrees {
+		width = max(width, len(wt.Branch))
+	}
+	for i, wt := range worktrees {
+		fmt.Fprintf(os.Stderr, "%3d) %-*s  %s\n", i+1, width, wt.Branch, wt.Path)
+	}
+	fmt.Fprintf(os.Stderr, "Numbers to keep, Enter to remove all, q to abort: ")
+
+	answer, err := bufio.NewReader(tty).ReadString('\n')
+	if err != nil && strings.TrimSpace(answer) == "" {
+		fmt.Fprintln(os.Stderr)
+		return nil, ErrNothingSelected
+	}
+	answer = strings.TrimSpace(answer)
+	if answer == "q" {
+		return nil, ErrNothingSelected
+	}
+
+	keep := make(map[int]bool)
+	for _, field := range strings.FieldsFunc(answer, func(r rune) bool { return r == ' ' || r == ',' }) {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 1 || n > len(worktrees) {
+			return nil, fmt.Errorf("%w: '%s' is not a number from 1 to %d", ErrNothingSelected, field, len(worktrees))
+		}
+		keep[n-1] = true
+	}
+
+	var selected []RemovableWorktree
+	for i, wt := range worktrees {
+		if !keep[i] {
+			selected = append(selected, wt)
+		}
+	}
+	return selected, nil
+}
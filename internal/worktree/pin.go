@@ -0,0 +1,125 @@
+package worktree
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/sokinpui/wt-go/internal/log"
+)
+
+// PinWorktree marks the worktree of branchName as pinned, so bulk cleanup
+// operations leave it alone. Pinned worktrees are recorded by path in the
+// wt.pinned file in the git common directory.
+func PinWorktree(branchName string) error {
+	return updatePinned(branchName, true)
+}
+
+// UnpinWorktree removes the pin from the worktree of branchName.
+func UnpinWorktree(branchName string) error {
+	return updatePinned(branchName, false)
+}
+
+// isPinned reports whether the worktree at path is pinned.
+func isPinned(path string) bool {
+	pinned, err := pinnedPaths()
+	if err != nil {
+		return false
+	}
+	return pinned[canonicalPath(path)]
+}
+
+func updatePinned(branchName string, pin bool) error {
+	if branchName == "" {
+		return fmt.Errorf("branch name cannot be empty")
+	}
+
+	unlock, err := lockRepo()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	worktreePath, err := FindWorktreePathForBranch(branchName)
+	if err != nil {
+		return fmt.Errorf("finding worktree for branch '%s': %w", branchName, err)
+	}
+	if worktreePath == "" {
+		return MissingWorktreeError(branchName)
+	}
+
+	pinned, err := pinnedPaths()
+	if err != nil {
+		return err
+	}
+
+	key := canonicalPath(worktreePath)
+	if pinned[key] == pin {
+		return nil // Already in the requested state.
+	}
+	if pin {
+		pinned[key] = true
+	} else {
+		delete(pinned, key)
+	}
+
+	if err := writePinnedPaths(pinned); err != nil {
+		return err
+	}
+
+	action := "pin"
+	if !pin {
+		action = "unpin"
+	}
+	log.Infof("worktree %s: %s", action, worktreePath)
+	return nil
+}
+
+// pinnedPaths reads the set of pinned worktree paths. A missing file means
+// nothing is pinned.
+func pinnedPaths() (map[string]bool, error) {
+	pinnedFile, err := getPinnedFilePath()
+	if err != nil {
+		return nil, err
+	}
+
+	pinned := make(map[string]bool)
+	content, err := os.ReadFile(pinnedFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return pinned, nil
+		}
+		return nil, fmt.Errorf("reading pinned file: %w", err)
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			pinned[line] = true
+		}
+	}
+	return pinned, nil
+}
+
+func writePinnedPaths(pinned map[string]bool) error {
+	pinnedFile, err := getPinnedFilePath()
+	if err != nil {
+		return err
+	}
+
+	paths := make([]string, 0, len(pinned))
+	for path := range pinned {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var content string
+	if len(paths) > 0 {
+		content = strings.Join(paths, "\n") + "\n"
+	}
+	if err := writeFileAtomic(pinnedFile, []byte(content), 0644); err != nil {
+		return fmt.Errorf("writing pinned file: %w", err)
+	}
+	return nil
+}
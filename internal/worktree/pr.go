@@ -0,0 +1,216 @@
+package worktree
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/sokinpui/wt-go/internal/git"
+	"github.com/sokinpui/wt-go/internal/log"
+)
+
+// defaultRemote is the remote branches are pushed to for pull requests and
+// tracked from on create.
+const defaultRemote = "origin"
+
+// PushForPullRequest pushes the branch of the current worktree to origin,
+// setting it as upstream, and returns the provider's URL for opening a pull
+// request (GitHub) or merge request (GitLab) against the default branch.
+// It refuses to push the default branch or a branch with no commits ahead
+// of it.
+func PushForPullRequest() (string, error) {
+	branch, err := CurrentBranch()
+	if err != nil {
+		return "", err
+	}
+
+	base, baseRef := defaultBranch()
+	if branch == base {
+		return "", fmt.Errorf("'%s' is the default branch; open a pull request from a feature branch", branch)
+	}
+	if baseRef != "" {
+		ahead, err := git.Exec("rev-list", "--count", baseRef+"..HEAD")
+		if err != nil {
+			return "", fmt.Errorf("comparing '%s' with '%s': %w", branch, baseRef, err)
+		}
+		if strings.TrimSpace(ahead) == "0" {
+			return "", fmt.Errorf("branch '%s' has no commits ahead of '%s'", branch, baseRef)
+		}
+	}
+
+	remoteURL, err := git.Exec("remote", "get-url", defaultRemote)
+	if err != nil {
+		return "", fmt.Errorf("no '%s' remote configured: %w", defaultRemote, err)
+	}
+	repo, err := ParseRemoteURL(remoteURL)
+	if err != nil {
+		return "", err
+	}
+
+	log.Infof("branch push: %s -> %s", branch, defaultRemote)
+	if _, err := git.Exec("push", "--set-upstream", defaultRemote, branch); err != nil {
+		return "", fmt.Errorf("pushing '%s' to '%s': %w", branch, defaultRemote, err)
+	}
+
+	return pullRequestURL(repo, base, branch), nil
+}
+
+// defaultPullRefTemplate is the ref GitHub keeps a pull request's head
+// commit at; GitLab's is defaultMergeRefTemplate. `{n}` stands for the
+// number.
+const (
+	defaultPullRefTemplate  = "pull/{n}/head"
+	defaultMergeRefTemplate = "merge-requests/{n}/head"
+)
+
+// FetchPullRequest fetches the head of pull request number into the local
+// branch `pr-<number>` and returns the branch name. The remote is
+// WTGO_PR_REMOTE, default origin, and the ref fetched is WTGO_PR_REF with
+// `{n}` replaced by the number, defaulting to GitHub's or, for a GitLab
+// remote, GitLab's ref. An existing branch is only fast-forwarded unless the
+// user confirms resetting it, see updatePullRequestBranch. A branch already
+// checked out in a worktree is not fetched into, as git refuses to; pull in
+// its worktree to update it.
+func FetchPullRequest(number string) (string, error) {
+	if number == "" || strings.Trim(number, "0123456789") != "" {
+		return "", fmt.Errorf("%w: pull request number '%s' is not a number", ErrInvalidBranch, number)
+	}
+	branch := "pr-" + number
+
+	if path, err := FindWorktreePathForBranch(branch); err != nil {
+		return "", err
+	} else if path != "" {
+		log.Infof("%s already has a worktree at %s; not fetching into it.", branch, path)
+		return branch, nil
+	}
+
+	remote := os.Getenv("WTGO_PR_REMOTE")
+	if remote == "" {
+		remote = defaultRemote
+	}
+	template := os.Getenv("WTGO_PR_REF")
+	if template == "" {
+		template = defaultPullRefTemplate
+		if remoteURL, err := git.Exec("remote", "get-url", remote); err == nil {
+			if repo, err := ParseRemoteURL(remoteURL); err == nil && repo.IsGitLab() {
+				template = defaultMergeRefTemplate
+			}
+		}
+	}
+	ref := strings.ReplaceAll(template, "{n}", number)
+
+	log.Infof("fetch: %s %s -> %s", remote, ref, branch)
+	if _, err := execWithRetry("fetch", remote, ref); err != nil {
+		return "", fmt.Errorf("fetching pull request %s (%s from %s): %w", number, ref, remote, err)
+	}
+	head, err := git.Exec("rev-parse", "--verify", "FETCH_HEAD^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("resolving fetched pull request %s: %w", number, err)
+	}
+	if err := updatePullRequestBranch(branch, strings.TrimSpace(head)); err != nil {
+		return "", err
+	}
+	return branch, nil
+}
+
+// updatePullRequestBranch points branch at the fetched pull request head,
+// creating it if needed. An update that is not a fast-forward, as when the
+// pull request was force-pushed, drops the branch's commits, so it is only
+// made after confirmation and reports the old commit for recovery.
+func updatePullRequestBranch(branch, head string) error {
+	if !branchExists(branch) {
+		if _, err := git.Exec("branch", branch, head); err != nil {
+			return fmt.Errorf("creating branch '%s': %w", branch, err)
+		}
+		return nil
+	}
+
+	old, err := git.Exec("rev-parse", "--verify", "refs/heads/"+branch)
+	if err != nil {
+		return fmt.Errorf("resolving branch '%s': %w", branch, err)
+	}
+	old = strings.TrimSpace(old)
+	if old == head {
+		return nil
+	}
+
+	rewritten := false
+	if _, err := git.Exec("merge-base", "--is-ancestor", old, head); err != nil {
+		rewritten = true
+		question := fmt.Sprintf("The pull request head is not a fast-forward of '%s'; reset it from %s to %s?", branch, shortSHA(old), shortSHA(head))
+		if !Confirm(question) {
+			return fmt.Errorf("not updating branch '%s': the fetched head is not a fast-forward", branch)
+		}
+	}
+	if _, err := git.Exec("update-ref", "refs/heads/"+branch, head, old); err != nil {
+		return fmt.Errorf("updating branch '%s': %w", branch, err)
+	}
+	log.Infof("branch update: %s (was %s)", branch, old)
+	if rewritten {
+		log.Infof("Recover the old commits with: git branch <name> %s", old)
+	}
+	return nil
+}
+
+// defaultBranch returns the name of the default branch and the ref to compare
+// against, preferring what origin/HEAD points at and falling back to a local
+// main or master. The ref is empty if no default branch can be found.
+func defaultBranch() (string, string) {
+	if out, err := git.Exec("symbolic-ref", "--quiet", "--short", "refs/remotes/"+defaultRemote+"/HEAD"); err == nil {
+		ref := strings.TrimSpace(out)
+		return strings.TrimPrefix(ref, defaultRemote+"/"), ref
+	}
+	for _, name := range []string{"main", "master"} {
+		if branchExists(name) {
+			return name, name
+		}
+	}
+	return "", ""
+}
+
+// pullRequestURL builds the "create pull request" page URL for branch.
+func pullRequestURL(repo RemoteRepo, base, branch string) string {
+	if repo.IsGitLab() {
+		query := url.Values{}
+		query.Set("merge_request[source_branch]", branch)
+		if base != "" {
+			query.Set("merge_request[target_branch]", base)
+		}
+		return repo.WebURL() + "/-/merge_requests/new?" + query.Encode()
+	}
+
+	if base == "" {
+		return repo.WebURL() + "/pull/new/" + escapeRefPath(branch)
+	}
+	return repo.WebURL() + "/compare/" + escapeRefPath(base) + "..." + escapeRefPath(branch) + "?expand=1"
+}
+
+// escapeRefPath escapes a branch name for use in a URL path, keeping the
+// slashes that separate its components.
+func escapeRefPath(branch string) string {
+	parts := strings.Split(branch, "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.Join(parts, "/")
+}
+
+// OpenInBrowser opens target with the platform's default handler.
+func OpenInBrowser(target string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", target)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
+	default:
+		cmd = exec.Command("xdg-open", target)
+	}
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("opening browser: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package worktree
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sokinpui/wt-go/internal/log"
+)
+
+// AssumeYes makes Confirm answer yes without asking, for unattended runs.
+var AssumeYes bool
+
+// Confirm asks a yes/no question on stderr and reads the answer from stdin.
+// It returns false without prompting when stdin is not a terminal, so
+// destructive operations never proceed unattended by accident, unless
+// AssumeYes is set.
+func Confirm(question string) bool {
+	if AssumeYes {
+		log.Infof("%s [y/N] yes (--yes)", question)
+		return true
+	}
+
+	stat, err := os.Stdin.Stat()
+	if err != nil || (stat.Mode()&os.ModeCharDevice) == 0 {
+		return false
+	}
+
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	reader := bufio.NewReader(os.Stdin)
+	answer, err := reader.ReadString('\n')
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
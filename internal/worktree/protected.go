@@ -0,0 +1,36 @@
+package worktree
+
+import (
+	"os"
+	"strings"
+)
+
+// defaultProtectedBranches are protected when WTGO_PROTECTED_BRANCHES is unset.
+const defaultProtectedBranches = "main,master"
+
+// protectedBranches returns the branches wtgo refuses to delete or rename:
+// those listed, comma-separated, in WTGO_PROTECTED_BRANCHES (main and master
+// if it is unset) and always the repository's default branch, see
+// defaultBranch.
+func protectedBranches() map[string]bool {
+	list, ok := os.LookupEnv("WTGO_PROTECTED_BRANCHES")
+	if !ok {
+		list = defaultProtectedBranches
+	}
+
+	protected := make(map[string]bool)
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			protected[name] = true
+		}
+	}
+	if base, _ := defaultBranch(); base != "" {
+		protected[base] = true
+	}
+	return protected
+}
+
+// isProtectedBranch reports whether branchName is one of protectedBranches.
+func isProtectedBranch(branchName string) bool {
+	return protectedBranches()[branchName]
+}
@@ -0,0 +1,57 @@
+package worktree
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// RemoteRepo identifies a repository on a hosting provider, as parsed from a
+// remote URL.
+type RemoteRepo struct {
+	Host string // e.g. "github.com"
+	Path string // e.g. "owner/repo", or "group/subgroup/repo" on GitLab
+}
+
+// IsGitLab reports whether the repository is hosted on GitLab, judged by
+// the host name.
+func (r RemoteRepo) IsGitLab() bool {
+	return strings.Contains(r.Host, "gitlab")
+}
+
+// WebURL returns the repository's web page.
+func (r RemoteRepo) WebURL() string {
+	return "https://" + r.Host + "/" + r.Path
+}
+
+// ParseRemoteURL parses the remote URL shapes used by GitHub and GitLab:
+// `git@host:owner/repo.git`, `ssh://git@host[:port]/owner/repo.git` and
+// `https://host/owner/repo(.git)`.
+func ParseRemoteURL(remoteURL string) (RemoteRepo, error) {
+	remoteURL = strings.TrimSpace(remoteURL)
+
+	var host, path string
+	if strings.Contains(remoteURL, "://") {
+		u, err := url.Parse(remoteURL)
+		if err != nil {
+			return RemoteRepo{}, fmt.Errorf("parsing remote URL '%s': %w", remoteURL, err)
+		}
+		host, path = u.Hostname(), u.Path
+	} else {
+		// scp-like syntax: [user@]host:path
+		userHost, p, ok := strings.Cut(remoteURL, ":")
+		if !ok {
+			return RemoteRepo{}, fmt.Errorf("unrecognized remote URL '%s'", remoteURL)
+		}
+		if _, h, ok := strings.Cut(userHost, "@"); ok {
+			userHost = h
+		}
+		host, path = userHost, p
+	}
+
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	if host == "" || !strings.Contains(path, "/") {
+		return RemoteRepo{}, fmt.Errorf("unrecognized remote URL '%s'", remoteURL)
+	}
+	return RemoteRepo{Host: host, Path: path}, nil
+}
@@ -0,0 +1,31 @@
+package worktree
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sokinpui/wt-go/internal/git"
+)
+
+// EnterRepo makes dir, which must be inside a git repository, the current
+// directory, so that wtgo works on that repository as `git -C dir` would.
+// It returns dir as an absolute path.
+func EnterRepo(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("resolving repository path '%s': %w", dir, err)
+	}
+	if info, err := os.Stat(abs); err != nil {
+		return "", fmt.Errorf("%w: '%s': %w", ErrNotGitRepo, abs, err)
+	} else if !info.IsDir() {
+		return "", fmt.Errorf("%w: '%s' is not a directory", ErrNotGitRepo, abs)
+	}
+	if err := os.Chdir(abs); err != nil {
+		return "", fmt.Errorf("changing to repository '%s': %w", abs, err)
+	}
+	if _, err := git.Exec("rev-parse", "--git-dir"); err != nil {
+		return "", fmt.Errorf("%w: '%s'", ErrNotGitRepo, abs)
+	}
+	return abs, nil
+}
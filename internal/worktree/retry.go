@@ -0,0 +1,80 @@
+package worktree
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sokinpui/wt-go/internal/git"
+	"github.com/sokinpui/wt-go/internal/log"
+)
+
+// defaultRetries is how often a git command failing with a network error is
+// retried unless WTGO_RETRIES says otherwise.
+const defaultRetries = 3
+
+// retryBaseDelay is the wait before the first retry; it doubles with each
+// further one.
+const retryBaseDelay = time.Second
+
+// networkErrorMarkers are fragments of the messages git prints for failures
+// that tend to go away on their own.
+var networkErrorMarkers = []string{
+	"could not resolve host",
+	"temporary failure in name resolution",
+	"connection timed out",
+	"connection refused",
+	"connection reset",
+	"network is unreachable",
+	"the remote end hung up unexpectedly",
+	"early eof",
+}
+
+// isNetworkError reports whether err is a git failure caused by the network.
+// Running past the --timeout deadline is not one.
+func isNetworkError(err error) bool {
+	var gitErr *git.Error
+	if !errors.As(err, &gitErr) || errors.Is(err, git.ErrTimeout) {
+		return false
+	}
+	stderr := strings.ToLower(gitErr.Stderr)
+	for _, marker := range networkErrorMarkers {
+		if strings.Contains(stderr, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// networkRetries returns how often to retry on network errors: WTGO_RETRIES
+// if it holds a non-negative number, else defaultRetries.
+func networkRetries() int {
+	value := os.Getenv("WTGO_RETRIES")
+	if value == "" {
+		return defaultRetries
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		log.Warnf("ignoring WTGO_RETRIES=%s; expected a non-negative number", value)
+		return defaultRetries
+	}
+	return n
+}
+
+// execWithRetry runs git like git.Exec, retrying with exponential backoff
+// while it fails with a network error. Other failures are returned at once.
+func execWithRetry(args ...string) (string, error) {
+	retries := networkRetries()
+	delay := retryBaseDelay
+	for attempt := 1; ; attempt++ {
+		output, err := git.Exec(args...)
+		if err == nil || attempt > retries || !isNetworkError(err) {
+			return output, err
+		}
+		log.Warnf("git %s failed with a network error; retrying in %s (%d/%d)", args[0], delay, attempt, retries)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
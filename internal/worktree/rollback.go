@@ -0,0 +1,66 @@
+package worktree
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/sokinpui/wt-go/internal/git"
+	"github.com/sokinpui/wt-go/internal/log"
+)
+
+// rollbackGrace is how long git gets to clean up after a create that ran
+// out of time.
+const rollbackGrace = 10 * time.Second
+
+// rollbackPartialWorktree cleans up after a create that failed midway, e.g.
+// when a post-checkout hook errors or the disk fills during checkout, leaving
+// a half-populated directory behind. preexisting reports whether path existed
+// before the create started; such directories are never deleted. createdBranch,
+// if not empty, is a branch the failed create made and is deleted on rollback.
+// The user is asked before anything is removed; otherwise the partial state
+// is reported together with how to clean it up. A create that ran out of time
+// is rolled back without asking, since nobody may be around to answer.
+func rollbackPartialWorktree(path string, preexisting bool, createdBranch string) {
+	if preexisting || !pathExists(path) {
+		return
+	}
+
+	log.Warnf("the failed create left a partial worktree at '%s'.", path)
+	if git.TimedOut() {
+		// The operation's deadline has passed, so the cleanup gets its own.
+		ctx, cancel := context.WithTimeout(context.Background(), rollbackGrace)
+		defer cancel()
+		git.SetContext(ctx)
+	} else if !Confirm("Roll back the partially created worktree?") {
+		log.Warnf("left in place. Clean up with `git worktree remove --force %s`, or `git worktree prune` and `wtgo gc --force`.", path)
+		return
+	}
+
+	if _, err := git.Exec("worktree", "remove", "--force", path); err != nil {
+		// git may never have registered the worktree; drop the directory and
+		// any administrative entry it left behind ourselves.
+		if err := os.RemoveAll(path); err != nil {
+			ReportError(ErrorCodeOf(err), err, "Error removing '%s': %v\n", path, err)
+			return
+		}
+		if _, err := git.Exec("worktree", "prune"); err != nil {
+			log.Warnf("could not prune worktree entries: %v", err)
+		}
+	}
+	log.Infof("worktree rollback: %s", path)
+
+	if createdBranch != "" {
+		if _, err := git.Exec("branch", "-D", createdBranch); err != nil {
+			log.Warnf("could not delete branch '%s' created by the failed create: %v", createdBranch, err)
+			return
+		}
+		log.Infof("branch delete: %s", createdBranch)
+	}
+}
+
+// pathExists reports whether anything exists at path.
+func pathExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,151 @@
+package worktree
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/sokinpui/wt-go/internal/git"
+	"github.com/sokinpui/wt-go/internal/log"
+)
+
+// StaleWorktree is a worktree git still records although its directory is
+// gone, e.g. because it was deleted by hand.
+type StaleWorktree struct {
+	Path   string
+	Branch string // Empty for a detached worktree.
+	Locked bool
+}
+
+// FindStaleWorktrees returns the worktrees whose directory no longer exists
+// or that git itself marks as prunable. Locked worktrees are returned with
+// Locked set; they are neither pruned nor have their branches deleted.
+func FindStaleWorktrees() ([]StaleWorktree, error) {
+	entries, err := listWorktrees()
+	if err != nil {
+		return nil, err
+	}
+
+	var stale []StaleWorktree
+	for _, entry := range entries {
+		if entry.Path == "" || !(entry.Prunable || !pathExists(entry.Path)) {
+			continue
+		}
+		stale = append(stale, StaleWorktree{Path: entry.Path, Branch: entry.Branch, Locked: entry.Locked})
+	}
+	return stale, nil
+}
+
+// PruneStaleWorktrees drops git's records of the stale worktrees found by
+// FindStaleWorktrees with `git worktree prune`, which keeps locked ones.
+func PruneStaleWorktrees(stale []StaleWorktree) error {
+	unlock, err := lockRepo()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, err := git.Exec("worktree", "prune"); err != nil {
+		return fmt.Errorf("pruning worktrees: %w", err)
+	}
+	for _, wt := range stale {
+		if wt.Locked {
+			log.Infof("Skipping worktree '%s': locked worktree.", wt.Path)
+			continue
+		}
+		log.Infof("worktree prune: %s", wt.Path)
+	}
+	return nil
+}
+
+// PruneWorktrees runs `git worktree prune` and returns the paths of the
+// worktree entries it removed, found by comparing the worktree list before
+// and after.
+func PruneWorktrees() ([]string, error) {
+	unlock, err := lockRepo()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	before, err := worktreePaths()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := git.Exec("worktree", "prune"); err != nil {
+		return nil, fmt.Errorf("pruning worktrees: %w", err)
+	}
+	after, err := worktreePaths()
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	for path := range before {
+		if !after[path] {
+			removed = append(removed, path)
+		}
+	}
+	sort.Strings(removed)
+	for _, path := range removed {
+		log.Infof("worktree prune: %s", path)
+	}
+	return removed, nil
+}
+
+// BranchSkipReason explains why DeleteStaleBranches keeps the branch of wt,
+// or returns an empty string if the branch would be deleted.
+func (wt StaleWorktree) BranchSkipReason() string {
+	switch {
+	case wt.Branch == "":
+		return "detached worktree"
+	case isProtectedBranch(wt.Branch):
+		return "protected branch"
+	case wt.Locked:
+		return "locked worktree"
+	case isPinned(wt.Path):
+		return "pinned worktree"
+	}
+	return ""
+}
+
+// DeleteStaleBranches deletes the branches of the given stale worktrees,
+// which must already be pruned. Branches of pinned or locked worktrees and
+// the main branches are kept. Without force, branches with unmerged commits are kept
+// too, as with `git branch -d`. It returns the first error encountered,
+// after attempting all.
+func DeleteStaleBranches(stale []StaleWorktree, force bool) error {
+	unlock, err := lockRepo()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	deleteFlag := "-d"
+	if force {
+		deleteFlag = "-D"
+	}
+
+	var firstErr error
+	for _, wt := range stale {
+		if wt.Branch == "" {
+			continue
+		}
+		if reason := wt.BranchSkipReason(); reason != "" {
+			log.Infof("Skipping branch '%s': %s.", wt.Branch, reason)
+			continue
+		}
+
+		if _, err := git.Exec("branch", deleteFlag, wt.Branch); err != nil {
+			ReportError(ErrorCodeOf(err), err, "Error deleting branch '%s': %v\n", wt.Branch, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		log.Infof("branch delete: %s", wt.Branch)
+		if err := forgetIssue(wt.Branch); err != nil {
+			log.Warnf("could not drop issue of branch '%s': %v", wt.Branch, err)
+		}
+	}
+	return firstErr
+}
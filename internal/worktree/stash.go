@@ -0,0 +1,61 @@
+package worktree
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sokinpui/wt-go/internal/git"
+	"github.com/sokinpui/wt-go/internal/log"
+)
+
+// autoStashMessage labels stashes created by wtgo so they are easy to find in `git stash list`.
+const autoStashMessage = "wtgo auto-stash"
+
+// StashCurrentChanges stashes the staged, unstaged, and untracked changes of
+// the current worktree. It returns the commit SHA of the new stash, or an
+// empty string if there was nothing to stash. The stash is reported on stderr
+// so it can always be restored.
+func StashCurrentChanges() (string, error) {
+	status, err := git.Exec("status", "--porcelain")
+	if err != nil {
+		return "", fmt.Errorf("checking for changes: %w", err)
+	}
+	if strings.TrimSpace(status) == "" {
+		return "", nil
+	}
+
+	if _, err := git.Exec("stash", "push", "--include-untracked", "-m", autoStashMessage); err != nil {
+		return "", fmt.Errorf("stashing changes: %w", err)
+	}
+
+	sha, err := git.Exec("rev-parse", "stash@{0}")
+	if err != nil {
+		return "", fmt.Errorf("changes were stashed but the stash could not be resolved; see `git stash list`: %w", err)
+	}
+	sha = strings.TrimSpace(sha)
+
+	log.Infof("stash create: %s (%s)", sha, autoStashMessage)
+	log.Infof("Restore it with: git stash apply %s", sha)
+	return sha, nil
+}
+
+// PopStash restores the stash with commit sha, as returned by
+// StashCurrentChanges, into the current worktree and drops it, e.g. after the
+// switch it was made for failed. Staged changes are restored as staged.
+func PopStash(sha string) error {
+	list, err := git.Exec("stash", "list", "--format=%H")
+	if err != nil {
+		return fmt.Errorf("listing stashes: %w", err)
+	}
+	for i, entry := range strings.Split(strings.TrimSpace(list), "\n") {
+		if entry != sha {
+			continue
+		}
+		if _, err := git.Exec("stash", "pop", "--index", fmt.Sprintf("stash@{%d}", i)); err != nil {
+			return fmt.Errorf("restoring stash %s; restore it with `git stash apply %s`: %w", shortSHA(sha), sha, err)
+		}
+		log.Infof("stash pop: %s", sha)
+		return nil
+	}
+	return fmt.Errorf("stash %s is no longer in `git stash list`", shortSHA(sha))
+}
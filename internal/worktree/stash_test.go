@@ -0,0 +1,50 @@
+package worktree
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPopStashRestoresChanges(t *testing.T) {
+	repo := newTestRepo(t)
+	t.Chdir(repo)
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(repo, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// An older stash must be left alone.
+	write("old.txt", "old\n")
+	runGit(t, repo, "stash", "push", "--quiet", "--include-untracked", "-m", "older")
+
+	write("staged.txt", "staged\n")
+	runGit(t, repo, "add", "staged.txt")
+	write("staged.txt", "staged\nmodified\n")
+	write("untracked.txt", "untracked\n")
+	const want = "AM staged.txt\n?? untracked.txt"
+
+	sha, err := StashCurrentChanges()
+	if err != nil || sha == "" {
+		t.Fatalf("StashCurrentChanges() = %q, %v", sha, err)
+	}
+	if status := runGit(t, repo, "status", "--porcelain"); status != "" {
+		t.Fatalf("status after stashing = %q, want clean", status)
+	}
+
+	if err := PopStash(sha); err != nil {
+		t.Fatalf("PopStash: %v", err)
+	}
+	if status := runGit(t, repo, "status", "--porcelain"); status != want {
+		t.Errorf("status after popping = %q, want %q", status, want)
+	}
+	if list := runGit(t, repo, "stash", "list", "--format=%s"); list != "On main: older" {
+		t.Errorf("stash list = %q, want only the older stash", list)
+	}
+
+	if err := PopStash(sha); err == nil {
+		t.Error("popping a stash that is gone succeeded")
+	}
+}
@@ -0,0 +1,107 @@
+package worktree
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sokinpui/wt-go/internal/git"
+)
+
+// WorktreeStatus is the state of one worktree as reported by
+// PrintWorktreeStatus. Ahead and Behind are nil when the branch has no
+// upstream, and Dirty is nil when the worktree directory is missing.
+type WorktreeStatus struct {
+	Branch string `json:"branch"` // Empty for a detached worktree.
+	Path   string `json:"path"`
+	Dirty  *bool  `json:"dirty"`
+	Ahead  *int   `json:"ahead"`
+	Behind *int   `json:"behind"`
+	Locked bool   `json:"locked"`
+}
+
+// PrintWorktreeStatus prints, for every worktree, its branch, whether it has
+// uncommitted changes, how far it is ahead of and behind its upstream, and
+// whether git has locked it, as an aligned table. Unknown values are shown
+// as "-". With JSONOutput set, it prints a JSON array of WorktreeStatus
+// instead. It returns the first error encountered.
+func PrintWorktreeStatus() error {
+	entries, err := listWorktrees()
+	if err != nil {
+		return err
+	}
+
+	var statuses []WorktreeStatus
+	for _, entry := range entries {
+		statuses = append(statuses, WorktreeStatus{Path: entry.Path, Branch: entry.Branch, Locked: entry.Locked})
+	}
+
+	for i := range statuses {
+		status := &statuses[i]
+		if !pathExists(status.Path) {
+			continue
+		}
+		changes, err := git.ExecIn(status.Path, "status", "--porcelain")
+		if err != nil {
+			return fmt.Errorf("checking status of '%s': %w", status.Path, err)
+		}
+		dirty := strings.TrimSpace(changes) != ""
+		status.Dirty = &dirty
+
+		// rev-list fails when there is no upstream, which is shown as unknown.
+		counts, err := git.ExecIn(status.Path, "rev-list", "--left-right", "--count", "@{u}...HEAD")
+		if err != nil {
+			continue
+		}
+		if fields := strings.Fields(counts); len(fields) == 2 {
+			behind, errBehind := strconv.Atoi(fields[0])
+			ahead, errAhead := strconv.Atoi(fields[1])
+			if errBehind == nil && errAhead == nil {
+				status.Ahead, status.Behind = &ahead, &behind
+			}
+		}
+	}
+
+	if JSONOutput {
+		if statuses == nil {
+			statuses = []WorktreeStatus{}
+		}
+		data, err := json.Marshal(statuses)
+		if err != nil {
+			return fmt.Errorf("encoding worktree status: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "BRANCH\tSTATE\tAHEAD\tBEHIND\tPATH")
+	for _, status := range statuses {
+		branch := status.Branch
+		if branch == "" {
+			branch = "(detached)"
+		}
+		state := "clean"
+		switch {
+		case status.Dirty == nil:
+			state = "missing"
+		case *status.Dirty:
+			state = "dirty"
+		}
+		if status.Locked {
+			state += ",locked"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", branch, state, countOrDash(status.Ahead), countOrDash(status.Behind), status.Path)
+	}
+	return w.Flush()
+}
+
+func countOrDash(n *int) string {
+	if n == nil {
+		return "-"
+	}
+	return strconv.Itoa(*n)
+}
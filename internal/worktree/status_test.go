@@ -0,0 +1,50 @@
+package worktree
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPrintWorktreeStatusChecksEachWorktree(t *testing.T) {
+	repo := newTestRepo(t)
+	dir := filepath.Dir(repo)
+	clean := filepath.Join(dir, "repo.wt", "clean")
+	dirty := filepath.Join(dir, "repo.wt", "dirty")
+	runGit(t, repo, "worktree", "add", "--quiet", "-b", "clean", clean)
+	runGit(t, repo, "worktree", "add", "--quiet", "-b", "dirty", dirty)
+	if err := os.WriteFile(filepath.Join(dirty, "new.txt"), []byte("x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Run from the clean worktree, so only per-worktree commands see the
+	// change.
+	t.Chdir(clean)
+	JSONOutput = true
+	t.Cleanup(func() { JSONOutput = false })
+
+	var err error
+	stdout, stderr := captureOutput(t, func() { err = PrintWorktreeStatus() })
+	if err != nil {
+		t.Fatalf("PrintWorktreeStatus: %v\nstderr:\n%s", err, stderr)
+	}
+	var statuses []WorktreeStatus
+	if err := json.Unmarshal([]byte(stdout), &statuses); err != nil {
+		t.Fatalf("decoding %q: %v", stdout, err)
+	}
+
+	want := map[string]bool{"main": false, "clean": false, "dirty": true}
+	if len(statuses) != len(want) {
+		t.Fatalf("got %d statuses, want %d: %+v", len(statuses), len(want), statuses)
+	}
+	for _, status := range statuses {
+		wantDirty, ok := want[status.Branch]
+		if !ok {
+			t.Errorf("unexpected worktree %+v", status)
+			continue
+		}
+		if status.Dirty == nil || *status.Dirty != wantDirty {
+			t.Errorf("%s: Dirty = %v, want %v", status.Branch, status.Dirty, wantDirty)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package worktree
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/sokinpui/wt-go/internal/git"
+	"github.com/sokinpui/wt-go/internal/log"
+)
+
+// recurseSubmodulesByDefault reports whether WTGO_RECURSE_SUBMODULES asks
+// for submodules to be initialized in every new worktree.
+func recurseSubmodulesByDefault() bool {
+	enabled, err := strconv.ParseBool(os.Getenv("WTGO_RECURSE_SUBMODULES"))
+	return err == nil && enabled
+}
+
+// updateSubmodules checks out the submodules of the new worktree at path,
+// recursively, streaming git's progress. A worktree without .gitmodules is
+// left alone. Failures are only warned about, since the worktree itself is
+// usable and the update can be rerun by hand.
+func updateSubmodules(path string) {
+	if !pathExists(filepath.Join(path, ".gitmodules")) {
+		return
+	}
+	log.Infof("submodule update: %s", path)
+	if err := git.ExecStreamingIn(path, "submodule", "update", "--init", "--recursive"); err != nil {
+		log.Warnf("initializing submodules in '%s': %v; run `git submodule update --init --recursive` there", path, err)
+	}
+}
@@ -0,0 +1,107 @@
+package worktree
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sokinpui/wt-go/internal/git"
+	"github.com/sokinpui/wt-go/internal/log"
+)
+
+// SyncOptions controls how SyncWorktree updates a branch from its upstream.
+type SyncOptions struct {
+	// Rebase rebases local commits onto the upstream instead of only
+	// fast-forwarding.
+	Rebase bool
+	// Autostash stashes local changes around the update instead of skipping
+	// dirty worktrees.
+	Autostash bool
+}
+
+// SyncWorktree updates the worktree of branchName from the branch's upstream
+// with `git pull`, fast-forward only unless opts.Rebase is set.
+func SyncWorktree(branchName string, opts SyncOptions) error {
+	worktreePath, err := worktreePathOrError(branchName)
+	if err != nil {
+		return err
+	}
+	return syncWorktree(branchName, worktreePath, opts)
+}
+
+// SyncAllWorktrees runs SyncWorktree for every worktree with a branch, except
+// the protected and default branches, which are only synced when named. Each
+// result is reported; the returned error says how many worktrees failed.
+func SyncAllWorktrees(opts SyncOptions) error {
+	entries, err := listWorktrees()
+	if err != nil {
+		return err
+	}
+
+	protected := protectedBranches()
+	failed := 0
+	for _, entry := range entries {
+		branch, path := entry.Branch, entry.Path
+		if branch == "" {
+			continue
+		}
+		if protected[branch] {
+			log.Infof("sync skip: %s (protected branch; name it to sync)", branch)
+			continue
+		}
+		if !pathExists(path) {
+			log.Infof("sync skip: %s (worktree directory is missing)", branch)
+			continue
+		}
+		if err := syncWorktree(branch, path, opts); err != nil {
+			ReportError(ErrorCodeOf(err), err, "sync fail: %s: %v\n", branch, err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d worktree(s) could not be synced", failed)
+	}
+	return nil
+}
+
+func syncWorktree(branchName, worktreePath string, opts SyncOptions) error {
+	if _, err := git.ExecIn(worktreePath, "rev-parse", "--verify", "--quiet", "@{upstream}"); err != nil {
+		log.Warnf("sync skip: %s (no upstream branch)", branchName)
+		return nil
+	}
+
+	if !opts.Autostash {
+		status, err := git.ExecIn(worktreePath, "status", "--porcelain", "--untracked-files=no")
+		if err != nil {
+			return fmt.Errorf("checking for changes in '%s': %w", worktreePath, err)
+		}
+		if strings.TrimSpace(status) != "" {
+			log.Warnf("sync skip: %s (uncommitted changes; commit them or use --autostash)", branchName)
+			return nil
+		}
+	}
+
+	before, _ := git.ExecIn(worktreePath, "rev-parse", "HEAD")
+
+	args := []string{"-C", worktreePath, "pull"}
+	if opts.Rebase {
+		args = append(args, "--rebase")
+	} else {
+		args = append(args, "--ff-only")
+	}
+	if opts.Autostash {
+		args = append(args, "--autostash")
+	}
+	if err := git.ExecStreaming(args...); err != nil {
+		return fmt.Errorf("pulling in '%s': %w", worktreePath, err)
+	}
+
+	after, _ := git.ExecIn(worktreePath, "rev-parse", "HEAD")
+	before, after = strings.TrimSpace(before), strings.TrimSpace(after)
+	if before == after {
+		log.Infof("sync: %s (up to date)", branchName)
+		return nil
+	}
+	log.Infof("sync: %s %s..%s", branchName, shortSHA(before), shortSHA(after))
+	return nil
+}
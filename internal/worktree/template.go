@@ -0,0 +1,82 @@
+package worktree
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// defaultNameTemplate names a worktree's directory after its branch alone.
+const defaultNameTemplate = "{branch}"
+
+// NameTemplate, if set, overrides WTGO_NAME_TEMPLATE and the
+// wtgo.nameTemplate config as the template for worktree directory names.
+var NameTemplate string
+
+// placeholderPattern matches the placeholders of a name template.
+var placeholderPattern = regexp.MustCompile(`\{[a-z]+\}`)
+
+// nameTemplate returns the template worktree directory names are rendered
+// from: NameTemplate, else WTGO_NAME_TEMPLATE, else the wtgo.nameTemplate
+// config, else defaultNameTemplate.
+func nameTemplate() string {
+	if NameTemplate != "" {
+		return NameTemplate
+	}
+	if template := os.Getenv("WTGO_NAME_TEMPLATE"); template != "" {
+		return template
+	}
+	if template := configValue("wtgo.nameTemplate"); template != "" {
+		return template
+	}
+	return defaultNameTemplate
+}
+
+// renderName substitutes branchName and the other placeholders into
+// template: {branch}, {repo} for the main repository's directory name,
+// {date} for today as YYYY-MM-DD and {user} for the current user name. The
+// result is sanitized afterwards like a branch name. A template must
+// contain {branch}, or every worktree would get the same directory.
+func renderName(template, branchName string) (string, error) {
+	if !strings.Contains(template, "{branch}") {
+		return "", fmt.Errorf("name template '%s' must contain {branch}", template)
+	}
+
+	var renderErr error
+	name := placeholderPattern.ReplaceAllStringFunc(template, func(placeholder string) string {
+		switch placeholder {
+		case "{branch}":
+			return branchName
+		case "{repo}":
+			root, err := mainRepoRoot()
+			if err != nil {
+				renderErr = err
+				return ""
+			}
+			return filepath.Base(root)
+		case "{date}":
+			return time.Now().Format("2006-01-02")
+		case "{user}":
+			if u, err := user.Current(); err == nil && u.Username != "" {
+				// Windows reports DOMAIN\user.
+				return u.Username[strings.LastIndex(u.Username, `\`)+1:]
+			}
+			if name := os.Getenv("USER"); name != "" {
+				return name
+			}
+			renderErr = fmt.Errorf("could not determine the user name for name template '%s'", template)
+			return ""
+		default:
+			renderErr = fmt.Errorf("unknown placeholder %s in name template '%s'; expected {branch}, {repo}, {date} or {user}", placeholder, template)
+			return ""
+		}
+	})
+	if renderErr != nil {
+		return "", renderErr
+	}
+	return name, nil
+}
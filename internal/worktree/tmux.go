@@ -0,0 +1,47 @@
+package worktree
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/sokinpui/wt-go/internal/log"
+)
+
+// defaultTmuxCommand opens a tmux window named after the branch, rooted at the worktree.
+const defaultTmuxCommand = "tmux new-window -c {path} -n {branch}"
+
+// InTmux reports whether wtgo is running inside a tmux session.
+func InTmux() bool {
+	return os.Getenv("TMUX") != ""
+}
+
+// OpenTmuxWindow runs the tmux command template for the given worktree.
+// The template comes from the `wtgo.tmuxCommand` git config key and defaults to
+// defaultTmuxCommand. It is split on whitespace, and the `{path}` and `{branch}`
+// placeholders are substituted per word, so paths with spaces need no quoting.
+func OpenTmuxWindow(branchName, path string) error {
+	template := configValue("wtgo.tmuxCommand")
+	if template == "" {
+		template = defaultTmuxCommand
+	}
+
+	replacer := strings.NewReplacer("{path}", path, "{branch}", branchName)
+	var args []string
+	for _, field := range strings.Fields(template) {
+		args = append(args, replacer.Replace(field))
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("wtgo.tmuxCommand is empty")
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	// Progress output follows --quiet; error output is always shown.
+	cmd.Stdout = log.Writer(log.LevelInfo)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("running %s: %w", strings.Join(args, " "), err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package worktree
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/sokinpui/wt-go/internal/log"
+)
+
+// SwitchToMainWorktree returns the path of the main worktree, the one the
+// repository was cloned or initialized in. Like CreateWorktreeAndBranch, it
+// records the current directory first when switching away from it, so that
+// `wtgo -` returns.
+func SwitchToMainWorktree() (string, error) {
+	unlock, err := lockRepo()
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	path, err := mainWorktreePath()
+	if err != nil {
+		return "", err
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		log.Warnf("could not get current working directory: %v", err)
+	} else if canonicalPath(wd) != canonicalPath(path) {
+		if err := saveCurrentWorktreeState(); err != nil {
+			log.Warnf("could not save current worktree state: %v", err)
+		}
+	}
+	return path, nil
+}
+
+// mainWorktreePath returns the path of the main worktree, which git always
+// lists first. A bare repository has no main worktree.
+func mainWorktreePath() (string, error) {
+	entries, err := listWorktrees()
+	if err != nil {
+		return "", err
+	}
+
+	if len(entries) == 0 || entries[0].Path == "" {
+		return "", fmt.Errorf("could not determine the main worktree")
+	}
+	if entries[0].Bare {
+		return "", fmt.Errorf("the repository is bare and has no main worktree")
+	}
+	return entries[0].Path, nil
+}
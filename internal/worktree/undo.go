@@ -0,0 +1,158 @@
+package worktree
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sokinpui/wt-go/internal/git"
+	"github.com/sokinpui/wt-go/internal/log"
+)
+
+// removal describes the most recent worktree removal, as recorded in the
+// wt.last-removed file in the git common directory.
+type removal struct {
+	Branch   string
+	Commit   string
+	Path     string
+	Upstream string
+}
+
+// removalOf returns what is needed to undo the removal of branchName and
+// its worktree at path. It must be called while the branch still exists.
+func removalOf(branchName, path string) (removal, error) {
+	sha, err := git.Exec("rev-parse", "--verify", "refs/heads/"+branchName)
+	if err != nil {
+		return removal{}, fmt.Errorf("resolving branch '%s': %w", branchName, err)
+	}
+	// A branch without an upstream is fine; there is just nothing to restore.
+	upstream, _ := git.Exec("rev-parse", "--abbrev-ref", "--symbolic-full-name", branchName+"@{upstream}")
+
+	return removal{
+		Branch:   branchName,
+		Commit:   strings.TrimSpace(sha),
+		Path:     path,
+		Upstream: strings.TrimSpace(upstream),
+	}, nil
+}
+
+// recordRemoval saves r, replacing any earlier record. It is called once the
+// removal is complete, so a failed one does not replace the record of the
+// last one that happened. The caller must hold the repository lock.
+func recordRemoval(r removal) error {
+	content := fmt.Sprintf("branch %s\ncommit %s\npath %s\n", r.Branch, r.Commit, r.Path)
+	if r.Upstream != "" {
+		content += fmt.Sprintf("upstream %s\n", r.Upstream)
+	}
+
+	removedFile, err := getLastRemovedFilePath()
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(removedFile, []byte(content), 0644); err != nil {
+		return fmt.Errorf("writing last removed file: %w", err)
+	}
+	return nil
+}
+
+// lastRemoval reads the record written by recordRemoval.
+func lastRemoval() (removal, error) {
+	removedFile, err := getLastRemovedFilePath()
+	if err != nil {
+		return removal{}, err
+	}
+
+	content, err := os.ReadFile(removedFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return removal{}, fmt.Errorf("no removal to undo")
+		}
+		return removal{}, fmt.Errorf("reading last removed file: %w", err)
+	}
+
+	var r removal
+	for _, line := range strings.Split(string(content), "\n") {
+		key, value, _ := strings.Cut(strings.TrimSuffix(line, "\r"), " ")
+		switch key {
+		case "branch":
+			r.Branch = value
+		case "commit":
+			r.Commit = value
+		case "path":
+			r.Path = value
+		case "upstream":
+			r.Upstream = value
+		}
+	}
+	if r.Branch == "" || r.Commit == "" || r.Path == "" {
+		return removal{}, fmt.Errorf("last removed file '%s' is incomplete", removedFile)
+	}
+	return r, nil
+}
+
+// UndoLastRemoval recreates the branch and worktree deleted by the most
+// recent RemoveWorktreeAndBranch and prints the worktree's path. Only that
+// one removal is covered. The branch is recreated at its recorded commit,
+// which works as long as the commit still exists: commits that were never
+// pushed or merged elsewhere become unreachable on removal and are deleted
+// once `git gc` prunes them, by default after two weeks (gc.pruneExpire).
+func UndoLastRemoval() error {
+	unlock, err := lockRepo()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	r, err := lastRemoval()
+	if err != nil {
+		return err
+	}
+
+	if branchExists(r.Branch) {
+		return fmt.Errorf("branch '%s' exists again; not restoring over it", r.Branch)
+	}
+	if pathExists(r.Path) {
+		return fmt.Errorf("'%s' already exists; not restoring the worktree over it", r.Path)
+	}
+	if _, err := git.Exec("cat-file", "-e", r.Commit+"^{commit}"); err != nil {
+		return fmt.Errorf("commit %s of branch '%s' no longer exists; it may have been garbage collected", r.Commit, r.Branch)
+	}
+
+	if _, err := git.Exec("branch", r.Branch, r.Commit); err != nil {
+		return fmt.Errorf("recreating branch '%s': %w", r.Branch, err)
+	}
+	log.Infof("branch create: %s (%s)", r.Branch, shortSHA(r.Commit))
+
+	if r.Upstream != "" {
+		if _, err := git.Exec("branch", "--set-upstream-to="+r.Upstream, r.Branch); err != nil {
+			log.Warnf("could not restore upstream '%s' of branch '%s': %v", r.Upstream, r.Branch, err)
+		}
+	}
+
+	if err := ensureParentDir(r.Path); err != nil {
+		return err
+	}
+	output, err := git.Exec("worktree", "add", r.Path, r.Branch)
+	if err != nil {
+		// Leave things as they were, so the undo can be retried.
+		if _, delErr := git.Exec("branch", "-D", r.Branch); delErr != nil {
+			return fmt.Errorf("recreating worktree '%s': %w; could not delete the recreated branch '%s' either: %v", r.Path, err, r.Branch, delErr)
+		}
+		return fmt.Errorf("recreating worktree '%s': %w", r.Path, err)
+	}
+	log.Infof("worktree create: %s", r.Path)
+	if strings.TrimSpace(output) != "" {
+		log.Infof("%s", output)
+	}
+
+	removedFile, err := getLastRemovedFilePath()
+	if err == nil {
+		err = os.Remove(removedFile)
+	}
+	if err != nil {
+		log.Warnf("could not clear the last removal record: %v", err)
+	}
+
+	PrintPath(r.Path)
+	return nil
+}
@@ -0,0 +1,68 @@
+package worktree
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFailedRemovalKeepsLastRecord(t *testing.T) {
+	repo := newTestRepo(t)
+	t.Chdir(repo)
+	wtDir := filepath.Join(filepath.Dir(repo), "repo.wt")
+	for _, branch := range []string{"a", "b"} {
+		runGit(t, repo, "worktree", "add", "--quiet", "-b", branch, filepath.Join(wtDir, branch))
+	}
+
+	captureOutput(t, func() {
+		if err := RemoveWorktreeAndBranch("a", false, false); err != nil {
+			t.Fatalf("removing a: %v", err)
+		}
+	})
+
+	// Untracked files make `git worktree remove` fail without --force.
+	if err := os.WriteFile(filepath.Join(wtDir, "b", "notes.txt"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := RemoveWorktreeAndBranch("b", false, false); err == nil {
+		t.Fatal("removing b with an untracked file succeeded")
+	}
+
+	r, err := lastRemoval()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Branch != "a" {
+		t.Errorf("last removal is of %q, want the one of a that happened", r.Branch)
+	}
+}
+
+func TestUndoLastRemovalDeletesBranchIfWorktreeFails(t *testing.T) {
+	repo := newTestRepo(t)
+	t.Chdir(repo)
+	path := filepath.Join(filepath.Dir(repo), "repo.wt", "a")
+	runGit(t, repo, "worktree", "add", "--quiet", "-b", "a", path)
+	captureOutput(t, func() {
+		if err := RemoveWorktreeAndBranch("a", false, false); err != nil {
+			t.Fatalf("removing a: %v", err)
+		}
+	})
+
+	// A worktree registered at the path but missing on disk makes `git
+	// worktree add` refuse it.
+	runGit(t, repo, "worktree", "add", "--quiet", "-b", "c", path)
+	if err := os.RemoveAll(path); err != nil {
+		t.Fatal(err)
+	}
+	worktreeList.valid = false
+
+	if err := UndoLastRemoval(); err == nil {
+		t.Fatal("UndoLastRemoval succeeded")
+	}
+	if branchExists("a") {
+		t.Error("the recreated branch a was left behind")
+	}
+	if _, err := lastRemoval(); err != nil {
+		t.Errorf("the removal record is gone: %v", err)
+	}
+}
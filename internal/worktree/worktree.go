@@ -1,29 +1,136 @@
 package worktree
 
 import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
 
 	"github.com/sokinpui/wt-go/internal/git"
+	"github.com/sokinpui/wt-go/internal/log"
 )
 
-// CreateWorktreeAndBranch handles creation and switching of Git worktrees.
-// If a worktree for the given branch already exists, it prints the path to that worktree.
-// This allows for easy switching, e.g., `cd $(wt <branch>)`.
-// If no worktree exists, it creates a new one. If the branch doesn't exist,
-// it creates the branch as well. After creation, it prints the new worktree's path.
-func CreateWorktreeAndBranch(branchName string) {
+// CreateOptions controls how CreateWorktree creates a worktree.
+type CreateOptions struct {
+	// BaseRef is where a newly created branch starts. Without it, a branch of
+	// the same name on origin is checked out and tracked, unless NoTrack is
+	// set; otherwise the branch starts at the current HEAD.
+	BaseRef string
+	// NoTrack creates a missing branch from HEAD even if origin has one of
+	// the same name, and sets no upstream when BaseRef is remote-tracking.
+	NoTrack bool
+	// Fresh creates a branch just deleted by DiscardBranch: it starts at
+	// BaseRef, or the default branch if that is empty, and tracks nothing,
+	// rather than picking up origin's copy of the discarded branch again.
+	Fresh bool
+	// CopyPatterns lists glob patterns, relative to the worktree root, of
+	// files to copy from the current worktree into a new one, e.g. gitignored
+	// `.env` files a fresh checkout lacks.
+	CopyPatterns []string
+	// BaseDir, if set, is the directory to create the worktree in instead of
+	// the collection directory.
+	BaseDir string
+	// Fetch updates origin's copy of a branch that does not exist locally
+	// before deciding whether to track it, so a stale remote-tracking ref is
+	// not checked out.
+	Fetch bool
+	// DryRun only works out where the worktree would go and prints the git
+	// command that would create it, changing nothing.
+	DryRun bool
+	// RecurseSubmodules initializes the submodules of a new worktree. It is
+	// implied by WTGO_RECURSE_SUBMODULES=1.
+	RecurseSubmodules bool
+	// NoSwitch leaves the worktree history alone, for worktrees created by
+	// scripts rather than to move into, so `wtgo -` is unaffected.
+	NoSwitch bool
+}
+
+// CreateWorktreeAndBranch handles creation and switching of Git worktrees
+// with CreateWorktree, printing the resulting path with PrintPath. That path
+// is the only thing written to stdout, while git's output and all
+// diagnostics go to stderr, so `cd "$(wtgo <branch>)"` is safe. Errors are
+// returned unreported. With opts.DryRun, only the plan is printed.
+func CreateWorktreeAndBranch(branchName string, opts CreateOptions) error {
+	path, err := CreateWorktree(branchName, opts)
+	if err != nil {
+		return err
+	}
+	if opts.DryRun {
+		return nil
+	}
+	PrintPath(path)
+	return nil
+}
+
+// CreateWorktree returns the path of the worktree for branchName, creating
+// it if there is none yet. If the branch doesn't exist, it is created as
+// well, as described for CreateOptions. Unless the worktree is the current
+// one or opts.NoSwitch is set, the current directory is recorded for
+// `wtgo -`. A new worktree first has its submodules initialized if asked to,
+// then gets the files matching opts.CopyPatterns copied into it and then the
+// post-create hook run in it; failures of either are only warnings. A create
+// that fails midway is rolled back.
+func CreateWorktree(branchName string, opts CreateOptions) (string, error) {
 	if branchName == "" {
-		fmt.Fprintf(os.Stderr, "Error: Branch name cannot be empty.\n")
-		return
+		return "", ErrEmptyBranch
+	}
+
+	if opts.Fresh {
+		opts.NoTrack = true
+		if opts.BaseRef == "" {
+			_, opts.BaseRef = defaultBranch()
+		}
+	}
+
+	baseRef := opts.BaseRef
+	if baseRef != "" {
+		if _, err := git.Exec("rev-parse", "--verify", "--quiet", baseRef+"^{commit}"); err != nil {
+			return "", fmt.Errorf("%w: base ref '%s' does not resolve to a commit", ErrInvalidBranch, baseRef)
+		}
 	}
 
+	// Only a branch that would track origin can be made stale; an existing
+	// local branch is checked out as is.
+	if opts.Fetch && baseRef == "" && !opts.NoTrack && !branchExists(branchName) {
+		if opts.DryRun {
+			log.Infof("would fetch %s/%s first; the plan uses the remote-tracking ref as it is now", defaultRemote, branchName)
+		} else if err := fetchBranch(branchName); err != nil {
+			if remoteBranchExists(branchName) {
+				return "", err
+			}
+			log.Warnf("%v", err)
+		}
+	}
+
+	unlock, err := lockRepo()
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
 	existingPath, err := FindWorktreePathForBranch(branchName)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error checking for existing worktree for branch '%s': %v\n", branchName, err)
-		return
+		return "", fmt.Errorf("checking for existing worktree for branch '%s': %w", branchName, err)
+	}
+
+	// Asking for the branch of the current worktree, possibly from one of its
+	// subdirectories, is a no-op and must not record the state.
+	if existingPath != "" {
+		if current, err := CurrentBranch(); err == nil && current == branchName {
+			if top, err := git.Exec("rev-parse", "--show-toplevel"); err == nil &&
+				canonicalPath(filepath.FromSlash(strings.TrimSpace(top))) == canonicalPath(existingPath) {
+				log.Infof("already in worktree for '%s'", branchName)
+				return existingPath, nil
+			}
+		}
 	}
 
 	isSwitching := false
@@ -32,11 +139,9 @@ func CreateWorktreeAndBranch(branchName string) {
 		if err != nil {
 			// If we can't get the current directory, we can't compare.
 			// To be safe, don't update the state.
-			fmt.Fprintf(os.Stderr, "Warning: could not get current working directory: %v\n", err)
+			log.Warnf("could not get current working directory: %v", err)
 		} else {
-			absWd, errWd := filepath.Abs(wd)
-			absExistingPath, errExisting := filepath.Abs(existingPath)
-			if errWd == nil && errExisting == nil && absWd != absExistingPath {
+			if canonicalPath(wd) != canonicalPath(existingPath) {
 				isSwitching = true
 			}
 		}
@@ -45,78 +150,304 @@ func CreateWorktreeAndBranch(branchName string) {
 		isSwitching = true
 	}
 
-	if isSwitching {
+	if opts.DryRun && existingPath != "" {
+		fmt.Printf("would switch to: %s\n", existingPath)
+		return existingPath, nil
+	}
+
+	if isSwitching && !opts.DryRun && !opts.NoSwitch {
 		if err := saveCurrentWorktreeState(); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: could not save current worktree state: %v\n", err)
+			log.Warnf("could not save current worktree state: %v", err)
 		}
 	}
 
 	if existingPath != "" {
-		fmt.Print(existingPath)
-		return
+		return existingPath, nil
 	}
 
-	repoRoot, err := git.Exec("rev-parse", "--path-format=absolute", "--git-common-dir")
+	newWorktreePath, err := createPath(branchName, opts.BaseDir)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: Not a git repository or cannot determine root: %v\n", err)
-		return
+		return "", err
+	}
+	if err := checkPathFree(newWorktreePath); err != nil {
+		return "", err
 	}
-	repoRoot = strings.TrimSpace(repoRoot)
-	repoRoot = filepath.Dir(repoRoot)
-
-	parentDir := filepath.Dir(repoRoot)
-	repoBaseName := filepath.Base(repoRoot)
-
-	worktreeCollectionDir := filepath.Join(parentDir, repoBaseName+".wt")
-	sanitizedBranchName := strings.ReplaceAll(branchName, "/", "_")
-	newWorktreePath := filepath.Join(worktreeCollectionDir, sanitizedBranchName)
-
-	_, err = git.Exec("rev-parse", "--verify", "--quiet", "refs/heads/"+branchName)
-	branchExists := err == nil
 
 	var gitArgs []string
 
-	if branchExists {
-		fmt.Fprintf(os.Stderr, "worktree create: %s\n", newWorktreePath)
+	// createdBranch names the branch this call creates, so a rollback can drop it again.
+	var createdBranch string
+	if branchExists(branchName) {
+		if baseRef != "" {
+			log.Warnf("branch '%s' already exists; ignoring base ref '%s'", branchName, baseRef)
+		}
+		log.Infof("worktree create: %s", newWorktreePath)
 		gitArgs = []string{"worktree", "add", newWorktreePath, branchName}
+	} else if remoteRef := defaultRemote + "/" + branchName; baseRef == "" && !opts.NoTrack && remoteBranchExists(branchName) {
+		log.Infof("branch create: %s (tracking %s)", branchName, remoteRef)
+		log.Infof("worktree create: %s", newWorktreePath)
+		gitArgs = []string{"worktree", "add", "--track", "-b", branchName, newWorktreePath, remoteRef}
+		createdBranch = branchName
 	} else {
-		fmt.Fprintf(os.Stderr, "branch create: %s\n", branchName)
-		fmt.Fprintf(os.Stderr, "worktree create: %s\n", newWorktreePath)
+		if baseRef != "" {
+			log.Infof("branch create: %s (from %s)", branchName, baseRef)
+		} else {
+			log.Infof("branch create: %s", branchName)
+		}
+		log.Infof("worktree create: %s", newWorktreePath)
 		gitArgs = []string{"worktree", "add", "-b", branchName, newWorktreePath}
+		if opts.NoTrack {
+			gitArgs = []string{"worktree", "add", "--no-track", "-b", branchName, newWorktreePath}
+		}
+		if baseRef != "" {
+			gitArgs = append(gitArgs, baseRef)
+		}
+		createdBranch = branchName
 	}
 
-	output, err := git.Exec(gitArgs...)
+	if opts.DryRun {
+		fmt.Printf("would run: %s\n", commandLine(append([]string{"git"}, gitArgs...)))
+		return newWorktreePath, nil
+	}
+
+	if err := ensureParentDir(newWorktreePath); err != nil {
+		return "", err
+	}
+
+	preexisting := pathExists(newWorktreePath)
+	// Checkouts of large repositories take a while; let git show its progress.
+	if err := git.ExecStreaming(gitArgs...); err != nil {
+		// git has already streamed why it failed; the error is returned after
+		// the rollback.
+		rollbackPartialWorktree(newWorktreePath, preexisting, createdBranch)
+		return "", fmt.Errorf("creating worktree for branch '%s': %w", branchName, err)
+	}
+	// The worktree is in place; what follows only touches its files and may
+	// take long, so other wtgo invocations need not wait for it.
+	unlock()
+	if opts.RecurseSubmodules || recurseSubmodulesByDefault() {
+		updateSubmodules(newWorktreePath)
+	}
+	copyMatchingFiles(opts.CopyPatterns, newWorktreePath)
+	if err := runPostCreateHook(newWorktreePath, branchName); err != nil {
+		log.Warnf("%v", err)
+	}
+	return newWorktreePath, nil
+}
+
+// commandLine joins args into a command line to show the user, quoting the
+// arguments a shell would split or expand.
+func commandLine(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n'\"\\$`*?[]{}()<>|&;#~") {
+			arg = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+		quoted[i] = arg
+	}
+	return strings.Join(quoted, " ")
+}
+
+// CreateOrphanWorktree creates a new worktree holding an orphan branch with no
+// history and an empty working tree, then prints the new worktree's path.
+// Git 2.42 and later support this natively via `git worktree add --orphan`;
+// older versions get a detached worktree that is converted with
+// `git checkout --orphan` and then cleared.
+func CreateOrphanWorktree(branchName string) error {
+	if branchName == "" {
+		return ErrEmptyBranch
+	}
+
+	unlock, err := lockRepo()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error creating worktree for branch '%s': %v\n%s\n", branchName, err, output)
-		return
+		return err
 	}
-	// Print any informational output from the git command to stderr.
-	if strings.TrimSpace(output) != "" {
-		fmt.Fprint(os.Stderr, output)
+	defer unlock()
+
+	if branchExists(branchName) {
+		return fmt.Errorf("%w: branch '%s' already exists; an orphan branch must be new", ErrInvalidBranch, branchName)
+	}
+
+	newWorktreePath, err := newWorktreePathForBranch(branchName)
+	if err != nil {
+		return err
+	}
+	if err := checkPathFree(newWorktreePath); err != nil {
+		return err
+	}
+
+	log.Infof("orphan branch create: %s", branchName)
+	log.Infof("worktree create: %s", newWorktreePath)
+
+	if err := ensureParentDir(newWorktreePath); err != nil {
+		return err
 	}
-	fmt.Print(newWorktreePath)
+
+	preexisting := pathExists(newWorktreePath)
+	if git.VersionAtLeast(2, 42) {
+		if err := git.ExecStreaming("worktree", "add", "--orphan", "-b", branchName, newWorktreePath); err != nil {
+			rollbackPartialWorktree(newWorktreePath, preexisting, "")
+			return fmt.Errorf("creating orphan worktree for branch '%s': %w", branchName, err)
+		}
+		// Only a created worktree is worth going back from.
+		if err := saveCurrentWorktreeState(); err != nil {
+			log.Warnf("could not save current worktree state: %v", err)
+		}
+		unlock()
+		if err := runPostCreateHook(newWorktreePath, branchName); err != nil {
+			log.Warnf("%v", err)
+		}
+		PrintPath(newWorktreePath)
+		return nil
+	}
+
+	if err := git.ExecStreaming("worktree", "add", "--detach", newWorktreePath); err != nil {
+		rollbackPartialWorktree(newWorktreePath, preexisting, "")
+		return fmt.Errorf("creating worktree for orphan branch '%s': %w", branchName, err)
+	}
+
+	if _, err := git.ExecIn(newWorktreePath, "checkout", "--quiet", "--orphan", branchName); err != nil {
+		rollbackPartialWorktree(newWorktreePath, preexisting, "")
+		return fmt.Errorf("creating orphan branch '%s' in '%s': %w", branchName, newWorktreePath, err)
+	}
+
+	// The orphan branch starts with the previous HEAD's files staged; clear
+	// both the index and the working tree so the branch is truly empty.
+	tracked, err := git.ExecIn(newWorktreePath, "ls-files")
+	if err != nil {
+		rollbackPartialWorktree(newWorktreePath, preexisting, "")
+		return fmt.Errorf("listing files in '%s': %w", newWorktreePath, err)
+	}
+	if strings.TrimSpace(tracked) != "" {
+		if _, err := git.ExecIn(newWorktreePath, "rm", "-r", "-f", "--quiet", "."); err != nil {
+			rollbackPartialWorktree(newWorktreePath, preexisting, "")
+			return fmt.Errorf("clearing working tree in '%s': %w", newWorktreePath, err)
+		}
+	}
+
+	if err := saveCurrentWorktreeState(); err != nil {
+		log.Warnf("could not save current worktree state: %v", err)
+	}
+	unlock()
+	if err := runPostCreateHook(newWorktreePath, branchName); err != nil {
+		log.Warnf("%v", err)
+	}
+	PrintPath(newWorktreePath)
+	return nil
+}
+
+// RemoveWorktreeAndBranch removes a Git worktree and deletes its associated
+// branch, unless keepBranch is set, in which case only the worktree goes and
+// the removal cannot be undone with `wtgo undo-rm`, as there is nothing to
+// restore but the directory. The `.wtgo/pre-remove` hook in the repository
+// root runs first and can veto the removal by exiting non-zero;
+// `.wtgo/post-remove` runs afterwards, and its failure is only a warning. A
+// branch without a worktree is deleted on its own after confirmation. Errors
+// are returned unreported.
+//
+// Without force, a branch `git branch -d` would refuse to delete is refused
+// before the worktree is touched, so the worktree's ignored files are not
+// lost to a removal that has to be rolled back.
+func RemoveWorktreeAndBranch(branchName string, force, keepBranch bool) error {
+	return removeWorktreeAndBranch(branchName, removeOptions{force: force, keepBranch: keepBranch})
+}
+
+// removeOptions controls removeWorktreeAndBranch.
+type removeOptions struct {
+	force      bool
+	keepBranch bool
+	// mergedInto is a ref the branch was found merged into by a bulk
+	// removal. The branch is deleted if it still is, whatever its upstream
+	// or HEAD, which `git branch -d` would check against instead.
+	mergedInto string
+	// unmerged deletes the branch even if it is not merged anywhere, e.g.
+	// because the user confirmed removing a branch whose upstream is gone.
+	unmerged bool
+}
+
+// branchDeleteFlag returns the `git branch` flag to delete branchName with,
+// or an error wrapping ErrUnmergedBranch if it must be kept. It is called
+// before anything is removed.
+func branchDeleteFlag(branchName string, opts removeOptions) (string, error) {
+	switch {
+	case opts.force || opts.unmerged:
+		return "-D", nil
+	case opts.mergedInto != "":
+		if _, err := git.Exec("merge-base", "--is-ancestor", "refs/heads/"+branchName, opts.mergedInto); err != nil {
+			return "", fmt.Errorf("%w: '%s' is no longer merged into '%s'; nothing was removed", ErrUnmergedBranch, branchName, opts.mergedInto)
+		}
+		return "-D", nil
+	}
+
+	// Mirror `git branch -d`: the branch must be merged into its upstream,
+	// or into HEAD if it has none.
+	target := "HEAD"
+	if _, err := git.Exec("rev-parse", "--verify", "--quiet", "refs/heads/"+branchName+"@{upstream}"); err == nil {
+		target = "refs/heads/" + branchName + "@{upstream}"
+	}
+	if _, err := git.Exec("merge-base", "--is-ancestor", "refs/heads/"+branchName, target); err != nil {
+		return "", fmt.Errorf("%w: '%s' is not merged into %s; nothing was removed; pass --force to delete it anyway", ErrUnmergedBranch, branchName, strings.TrimPrefix(target, "refs/heads/"))
+	}
+	return "-d", nil
 }
 
-// RemoveWorktreeAndBranch removes a Git worktree and deletes its associated branch.
-func RemoveWorktreeAndBranch(branchName string, force bool) {
+func removeWorktreeAndBranch(branchName string, opts removeOptions) error {
+	force, keepBranch := opts.force, opts.keepBranch
 	if branchName == "" {
-		fmt.Fprintf(os.Stderr, "Error: Branch name cannot be empty.\n")
-		return
+		return ErrEmptyBranch
 	}
 
-	if branchName == "main" || branchName == "master" {
-		fmt.Fprintf(os.Stderr, "Error: Deleting the '%s' branch is not allowed.\n", branchName)
-		return
+	if !keepBranch && isProtectedBranch(branchName) {
+		return fmt.Errorf("%w: deleting the '%s' branch is not allowed", ErrProtectedBranch, branchName)
 	}
 
+	unlock, err := lockRepo()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	worktreePath, err := FindWorktreePathForBranch(branchName)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error finding worktree for branch '%s': %v\n", branchName, err)
-		return
+		return fmt.Errorf("finding worktree for branch '%s': %w", branchName, err)
 	}
 	if worktreePath == "" {
-		fmt.Fprintf(os.Stderr, "Error: No worktree found for branch '%s'.\n", branchName)
-		return
+		if keepBranch || !branchExists(branchName) {
+			return MissingWorktreeError(branchName)
+		}
+		return deleteLeftoverBranch(branchName, force)
+	}
+
+	if locked, reason, err := worktreeLock(worktreePath); err != nil {
+		return err
+	} else if locked {
+		if reason == "" {
+			reason = "no reason given"
+		}
+		return fmt.Errorf("%w: '%s' (%s); run `wtgo unlock %s` first", ErrWorktreeLocked, worktreePath, reason, branchName)
+	}
+
+	if force {
+		if err := confirmForcedRemoval(worktreePath); err != nil {
+			return err
+		}
+	}
+
+	deleteFlag := ""
+	if !keepBranch {
+		if deleteFlag, err = branchDeleteFlag(branchName, opts); err != nil {
+			return err
+		}
+	}
+
+	if err := runHook("pre-remove", worktreePath, branchName); err != nil {
+		return fmt.Errorf("%w; removal of '%s' aborted", err, worktreePath)
+	}
+
+	var undo removal
+	var undoErr error
+	if !keepBranch {
+		undo, undoErr = removalOf(branchName, worktreePath)
 	}
 
 	removeArgs := []string{"worktree", "remove"}
@@ -127,164 +458,889 @@ func RemoveWorktreeAndBranch(branchName string, force bool) {
 
 	output, err := git.Exec(removeArgs...)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error removing worktree '%s': %v\n%s\n", worktreePath, err, output)
-		return
+		return fmt.Errorf("removing worktree '%s': %w", worktreePath, err)
 	}
-	fmt.Fprintf(os.Stderr, "worktree remove: %s\n", worktreePath)
+	log.Infof("worktree remove: %s", worktreePath)
 	if strings.TrimSpace(output) != "" {
-		fmt.Fprint(os.Stderr, output)
+		log.Infof("%s", output)
 	}
+	removeEmptyParents(worktreePath)
 
-	deleteFlag := "-d"
-	if force {
-		deleteFlag = "-D"
+	if keepBranch {
+		unlock()
+		if err := runHook("post-remove", worktreePath, branchName); err != nil {
+			log.Warnf("%v", err)
+		}
+		return nil
 	}
 
 	output, err = git.Exec("branch", deleteFlag, branchName)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error deleting branch '%s': %v\n%s\n", branchName, err, output)
+		deleteErr := fmt.Errorf("deleting branch '%s': %w", branchName, err)
 
 		// Branch deletion failed, attempt to restore worktree to leave the user in a consistent state.
-		fmt.Fprintf(os.Stderr, "Attempting to restore worktree at '%s'...\n", worktreePath)
+		log.Infof("Attempting to restore worktree at '%s'...", worktreePath)
 		recreateArgs := []string{"worktree", "add", worktreePath, branchName}
 		recreateOutput, recreateErr := git.Exec(recreateArgs...)
 		if recreateErr != nil {
-			fmt.Fprintf(os.Stderr, "FATAL: Could not restore worktree for branch '%s'. Please check your repository state.\nError: %v\n%s\n", branchName, recreateErr, recreateOutput)
-		} else {
-			fmt.Fprintf(os.Stderr, "Worktree for branch '%s' restored successfully.\n", branchName)
-			fmt.Fprint(os.Stderr, recreateOutput)
+			return fmt.Errorf("%w; could not restore its worktree either, please check your repository state: %v", deleteErr, recreateErr)
 		}
-		return
+		log.Infof("Worktree for branch '%s' restored successfully.", branchName)
+		log.Infof("%s", recreateOutput)
+		return deleteErr
 	}
-	fmt.Fprintf(os.Stderr, "branch delete: %s\n", branchName)
+	log.Infof("branch delete: %s", branchName)
 	if strings.TrimSpace(output) != "" {
-		fmt.Fprint(os.Stderr, output)
+		log.Infof("%s", output)
+	}
+	if undoErr == nil {
+		undoErr = recordRemoval(undo)
+	}
+	if undoErr != nil {
+		log.Warnf("could not record removal for `wtgo undo-rm`: %v", undoErr)
+	}
+	if err := forgetIssue(branchName); err != nil {
+		log.Warnf("could not drop issue of branch '%s': %v", branchName, err)
+	}
+	unlock()
+	if err := runHook("post-remove", worktreePath, branchName); err != nil {
+		log.Warnf("%v", err)
+	}
+	return nil
+}
+
+// deleteLeftoverBranch deletes branchName, which has no worktree, e.g.
+// because its worktree was removed by hand, after confirmation. Like
+// `git branch -d`, it refuses an unmerged branch unless force is set.
+func deleteLeftoverBranch(branchName string, force bool) error {
+	sha, err := git.Exec("rev-parse", "--verify", "--quiet", "refs/heads/"+branchName)
+	if err != nil {
+		return fmt.Errorf("resolving branch '%s': %w", branchName, err)
+	}
+	if !Confirm(fmt.Sprintf("Branch '%s' has no worktree. Delete the branch at %s?", branchName, shortSHA(strings.TrimSpace(sha)))) {
+		return fmt.Errorf("%w: branch '%s' has no worktree and was kept; pass --yes to delete it", ErrWorktreeNotFound, branchName)
+	}
+
+	deleteFlag := "-d"
+	if force {
+		deleteFlag = "-D"
+	}
+	if _, err := git.Exec("branch", deleteFlag, branchName); err != nil {
+		return fmt.Errorf("deleting branch '%s': %w", branchName, err)
+	}
+	log.Infof("branch delete: %s", branchName)
+	if err := forgetIssue(branchName); err != nil {
+		log.Warnf("could not drop issue of branch '%s': %v", branchName, err)
+	}
+	return nil
+}
+
+// RemoveCurrentWorktree removes the worktree the current directory is in,
+// and its branch, as RemoveWorktreeAndBranch does. It moves to the main
+// worktree first, so git keeps working once the directory is gone, and
+// returns the main worktree's path for the caller to switch to. The main
+// worktree itself and detached worktrees are refused.
+func RemoveCurrentWorktree(force, keepBranch bool) (string, error) {
+	branch, mainPath, err := currentRemovableWorktree()
+	if err != nil {
+		return "", err
+	}
+	if err := os.Chdir(mainPath); err != nil {
+		return "", fmt.Errorf("changing to the main worktree '%s': %w", mainPath, err)
+	}
+	if err := RemoveWorktreeAndBranch(branch, force, keepBranch); err != nil {
+		return "", err
+	}
+	return mainPath, nil
+}
+
+// CurrentRemovableBranch returns the branch RemoveCurrentWorktree would
+// remove, or the error it would refuse with.
+func CurrentRemovableBranch() (string, error) {
+	branch, _, err := currentRemovableWorktree()
+	return branch, err
+}
+
+// currentRemovableWorktree returns the branch of the current worktree and
+// the path of the main worktree, refusing the main worktree itself and
+// detached worktrees.
+func currentRemovableWorktree() (string, string, error) {
+	top, err := git.Exec("rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %w", ErrNotGitRepo, err)
+	}
+	currentTop := canonicalPath(filepath.FromSlash(strings.TrimSpace(top)))
+
+	mainPath, err := mainWorktreePath()
+	if err != nil {
+		return "", "", err
+	}
+	if canonicalPath(mainPath) == currentTop {
+		return "", "", fmt.Errorf("%w: the current worktree is the main worktree, which cannot be removed", ErrProtectedBranch)
+	}
+
+	entries, err := listWorktrees()
+	if err != nil {
+		return "", "", err
+	}
+	var current *worktreeEntry
+	for i := range entries {
+		if canonicalPath(entries[i].Path) == currentTop {
+			current = &entries[i]
+			break
+		}
+	}
+	if current == nil {
+		return "", "", fmt.Errorf("%w: git does not list '%s' as a worktree", ErrWorktreeNotFound, currentTop)
+	}
+	if current.Branch == "" {
+		return "", "", fmt.Errorf("the current worktree has no branch checked out; remove it with `wtgo --rm --detach %s`", current.Path)
+	}
+	return current.Branch, mainPath, nil
+}
+
+// RemovalPlan describes what RemoveWorktreeAndBranch would do for
+// branchName, e.g. "remove: feat (/path/to/feat)", without changing
+// anything, or returns the error it would fail with up front.
+func RemovalPlan(branchName string, keepBranch bool) (string, error) {
+	if branchName == "" {
+		return "", ErrEmptyBranch
+	}
+	if !keepBranch && isProtectedBranch(branchName) {
+		return "", fmt.Errorf("%w: deleting the '%s' branch is not allowed", ErrProtectedBranch, branchName)
+	}
+
+	worktreePath, err := FindWorktreePathForBranch(branchName)
+	if err != nil {
+		return "", fmt.Errorf("finding worktree for branch '%s': %w", branchName, err)
+	}
+	if worktreePath == "" {
+		if keepBranch || !branchExists(branchName) {
+			return "", MissingWorktreeError(branchName)
+		}
+		return fmt.Sprintf("delete branch: %s (no worktree)", branchName), nil
+	}
+	if locked, _, err := worktreeLock(worktreePath); err != nil {
+		return "", err
+	} else if locked {
+		return "", fmt.Errorf("%w: '%s'; run `wtgo unlock %s` first", ErrWorktreeLocked, worktreePath, branchName)
+	}
+	if keepBranch {
+		return fmt.Sprintf("remove worktree: %s (keeping branch %s)", worktreePath, branchName), nil
+	}
+	return fmt.Sprintf("remove: %s (%s)", branchName, worktreePath), nil
+}
+
+// confirmForcedRemoval warns if the worktree at path has uncommitted changes
+// or untracked files, which a forced removal discards, and asks before going
+// on. Without a terminal to ask on, it refuses unless AssumeYes is set. The
+// refusal is returned as an error wrapping ErrDirtyWorktree.
+func confirmForcedRemoval(path string) error {
+	status, err := git.ExecIn(path, "status", "--porcelain")
+	if err != nil {
+		// An unreadable worktree is what --force is for; git decides.
+		log.Debugf("could not check '%s' for changes: %v", path, err)
+		return nil
+	}
+
+	changed := 0
+	for _, line := range strings.Split(status, "\n") {
+		if strings.TrimSpace(line) != "" {
+			changed++
+		}
+	}
+	if changed == 0 {
+		return nil
+	}
+
+	log.Warnf("'%s' has %d modified or untracked files; --force will discard them.", path, changed)
+	if !Confirm("Remove it anyway?") {
+		return fmt.Errorf("%w: removal of '%s' aborted; commit or stash the changes, or pass --yes", ErrDirtyWorktree, path)
+	}
+	return nil
+}
+
+// newWorktreePathForBranch computes where the worktree for branchName is
+// created inside the collection directory, see worktreePathIn.
+func newWorktreePathForBranch(branchName string) (string, error) {
+	worktreeCollectionDir, err := collectionDir()
+	if err != nil {
+		return "", err
+	}
+	return worktreePathIn(worktreeCollectionDir, branchName)
+}
+
+// createPath returns where CreateWorktree puts the worktree for branchName:
+// inside baseDir, made absolute, if it is set, else in the collection
+// directory.
+func createPath(branchName, baseDir string) (string, error) {
+	if baseDir == "" {
+		return newWorktreePathForBranch(branchName)
+	}
+	abs, err := filepath.Abs(baseDir)
+	if err != nil {
+		return "", fmt.Errorf("resolving base directory '%s': %w", baseDir, err)
+	}
+	return worktreePathIn(abs, branchName)
+}
+
+// worktreePathIn computes where the worktree for branchName is created
+// inside dir. By default slashes in the branch name are flattened to
+// underscores, so `feat/x` lives in `feat_x`; with WTGO_SANITIZE or
+// wtgo.sanitize set to `nested`, they become directory levels instead, so
+// `feat/x` lives in `feat/x` and cannot collide with a branch named `feat_x`.
+// On Windows, characters git allows in branch names but Windows forbids in
+// file names are flattened too. The name is rendered from the name template
+// first, see renderName.
+func worktreePathIn(dir, branchName string) (string, error) {
+	sanitizedBranchName, err := renderName(nameTemplate(), branchName)
+	if err != nil {
+		return "", err
+	}
+	if runtime.GOOS == "windows" {
+		sanitizedBranchName = strings.NewReplacer("<", "_", ">", "_", "\"", "_", "|", "_").Replace(sanitizedBranchName)
+	}
+	switch mode := sanitizeMode(); mode {
+	case "", "flatten":
+		sanitizedBranchName = strings.ReplaceAll(sanitizedBranchName, "/", "_")
+	case "nested":
+		sanitizedBranchName = filepath.FromSlash(sanitizedBranchName)
+	default:
+		return "", fmt.Errorf("unknown branch name sanitization '%s'; expected 'flatten' or 'nested'", mode)
+	}
+	return filepath.Join(dir, sanitizedBranchName), nil
+}
+
+// sanitizeMode returns how slashes in branch names map to directories: the
+// WTGO_SANITIZE environment variable, else the wtgo.sanitize config.
+func sanitizeMode() string {
+	if mode := os.Getenv("WTGO_SANITIZE"); mode != "" {
+		return mode
 	}
+	return configValue("wtgo.sanitize")
+}
+
+// checkPathFree returns an error wrapping ErrWorktreeExists if anything but
+// an empty directory is at path, where git would refuse to add a worktree.
+func checkPathFree(path string) error {
+	entries, err := os.ReadDir(path)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && len(entries) == 0) {
+		return nil
+	}
+	return fmt.Errorf("%w: '%s' is in the way", ErrWorktreeExists, path)
+}
+
+// ensureParentDir creates the directory that will contain the worktree at
+// path, but not path itself, which git insists on creating. Older git
+// versions do not create missing parents, and a permission problem reported
+// here is far clearer than git's own failure.
+func ensureParentDir(path string) error {
+	parent := filepath.Dir(path)
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		if os.IsPermission(err) {
+			return fmt.Errorf("permission denied creating worktree directory '%s': %w", parent, err)
+		}
+		return fmt.Errorf("could not create worktree directory '%s': %w", parent, err)
+	}
+	return nil
+}
+
+// collectionDir returns the wtgo-managed directory holding new worktrees.
+// By default it is a sibling `<repo>.wt` directory next to the main
+// repository. With wtgo.layout=xdg it is `<repo>-<hash>` inside the `wtgo`
+// directory of the user cache directory ($XDG_CACHE_HOME on Linux), where
+// the hash of the repository path keeps same-named repositories apart. A
+// global root configured via WTGO_WORKTREE_DIR, WTGO_GLOBAL_ROOT or
+// wtgo.globalRoot takes precedence over both and yields `<root>/<repo>-<hash>`, hashed likewise.
+//
+// Worktrees are always found through git's own records, so changing these
+// settings only affects where new worktrees are created; existing ones stay
+// put and keep working, though `wtgo gc` only inspects the current location.
+func collectionDir() (string, error) {
+	repoRoot, err := mainRepoRoot()
+	if err != nil {
+		return "", err
+	}
+
+	parentDir := filepath.Dir(repoRoot)
+	repoBaseName := filepath.Base(repoRoot)
+
+	globalRoot, err := globalRootDir()
+	if err != nil {
+		return "", err
+	}
+	if globalRoot != "" {
+		return filepath.Join(globalRoot, repoDirName(repoRoot)), nil
+	}
+
+	switch layout := configValue("wtgo.layout"); layout {
+	case "", "sibling":
+		return filepath.Join(parentDir, repoBaseName+".wt"), nil
+	case "xdg":
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return "", fmt.Errorf("locating the user cache directory for wtgo.layout=xdg: %w", err)
+		}
+		return filepath.Join(cacheDir, "wtgo", repoDirName(repoRoot)), nil
+	default:
+		return "", fmt.Errorf("unknown wtgo.layout '%s'; expected 'sibling' or 'xdg'", layout)
+	}
+}
+
+// repoDirName names the directory of the repository at repoRoot inside a
+// directory shared by many repositories: `<repo>-<hash>`, where the hash of
+// the repository path keeps same-named repositories apart.
+func repoDirName(repoRoot string) string {
+	sum := sha256.Sum256([]byte(canonicalPath(repoRoot)))
+	return filepath.Base(repoRoot) + "-" + hex.EncodeToString(sum[:])[:12]
+}
+
+// globalRootDir returns the configured global worktree root as an absolute
+// path, or an empty string if none is set. The WTGO_WORKTREE_DIR and then
+// WTGO_GLOBAL_ROOT environment variables take precedence over the
+// wtgo.globalRoot config, and a leading `~/` is expanded to the home
+// directory.
+func globalRootDir() (string, error) {
+	root := os.Getenv("WTGO_WORKTREE_DIR")
+	if root == "" {
+		root = os.Getenv("WTGO_GLOBAL_ROOT")
+	}
+	if root == "" {
+		root = configValue("wtgo.globalRoot")
+	}
+	if root == "" {
+		return "", nil
+	}
+
+	if root == "~" || strings.HasPrefix(root, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("expanding global worktree root '%s': %w", root, err)
+		}
+		root = filepath.Join(home, strings.TrimPrefix(root, "~"))
+	}
+
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return "", fmt.Errorf("resolving global worktree root '%s': %w", root, err)
+	}
+	return abs, nil
+}
+
+// remoteBranchExists reports whether origin has a branch named branchName,
+// as of the last fetch.
+func remoteBranchExists(branchName string) bool {
+	_, err := git.Exec("rev-parse", "--verify", "--quiet", "refs/remotes/"+defaultRemote+"/"+branchName)
+	return err == nil
+}
+
+// branchExists reports whether a local branch named branchName exists.
+func branchExists(branchName string) bool {
+	_, err := git.Exec("rev-parse", "--verify", "--quiet", "refs/heads/"+branchName)
+	return err == nil
+}
+
+// CurrentBranch returns the branch checked out in the current worktree, or
+// an error if HEAD is detached.
+func CurrentBranch() (string, error) {
+	out, err := git.Exec("symbolic-ref", "--quiet", "--short", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("the current worktree has no branch checked out")
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// CurrentName returns the branch checked out in the current worktree or, if
+// HEAD is detached, its abbreviated commit prefixed with '@'.
+func CurrentName() (string, error) {
+	if branch, err := CurrentBranch(); err == nil {
+		return branch, nil
+	}
+	out, err := git.Exec("rev-parse", "--verify", "--quiet", "--short", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrNotGitRepo, err)
+	}
+	return "@" + strings.TrimSpace(out), nil
+}
+
+// MissingWorktreeError describes why no worktree was found for branchName,
+// distinguishing a branch without a worktree from a name that matches nothing.
+// The error wraps ErrWorktreeNotFound.
+func MissingWorktreeError(branchName string) error {
+	if branchExists(branchName) {
+		return fmt.Errorf("%w: branch '%s' exists but has no worktree; run `wtgo %s` to create one", ErrWorktreeNotFound, branchName, branchName)
+	}
+	return fmt.Errorf("%w: no branch or worktree named '%s'", ErrWorktreeNotFound, branchName)
+}
+
+// mainRepoRoot returns the root of the main repository, i.e. the directory
+// containing the git common directory, regardless of which worktree we are in.
+func mainRepoRoot() (string, error) {
+	gitCommonDir, err := git.Exec("rev-parse", "--path-format=absolute", "--git-common-dir")
+	if err != nil {
+		return "", fmt.Errorf("%w or cannot determine root: %w", ErrNotGitRepo, err)
+	}
+	return filepath.Dir(filepath.FromSlash(strings.TrimSpace(gitCommonDir))), nil
 }
 
 // FindWorktreePathForBranch parses `git worktree list --porcelain` to find the path
-// of the worktree associated with the given branch name.
+// of the worktree associated with the given branch name. The main worktree,
+// listed first, matches like any other, so its root is returned for the
+// branch checked out there. It returns an empty path if there is none; bare
+// and detached worktrees never match.
 func FindWorktreePathForBranch(branchName string) (string, error) {
-	output, err := git.Exec("worktree", "list", "--porcelain")
+	if branchName == "" {
+		return "", nil
+	}
+
+	entries, err := listWorktrees()
 	if err != nil {
-		return "", fmt.Errorf("failed to list worktrees: %w", err)
+		return "", err
 	}
 
-	lines := strings.Split(output, "\n")
-	var currentPath string
-	var currentBranch string
+	for _, entry := range entries {
+		// A bare or detached entry has no branch, whatever lines follow it.
+		if !entry.Bare && !entry.Detached && entry.Branch == branchName {
+			return entry.Path, nil
+		}
+	}
+	return "", nil
+}
 
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			if currentPath != "" && currentBranch == branchName {
-				return currentPath, nil
-			}
-			currentPath = ""
-			currentBranch = ""
+// worktreeEntry is one worktree as listed by `git worktree list --porcelain`.
+type worktreeEntry struct {
+	Path       string
+	Head       string // The commit checked out; empty for bare entries.
+	Branch     string // Empty for bare and detached entries.
+	Bare       bool
+	Detached   bool
+	Locked     bool
+	LockReason string
+	Prunable   bool // Git considers the worktree's directory gone.
+}
+
+// worktreeList caches what listWorktrees read, along with the git.Changes
+// count at the time.
+var worktreeList struct {
+	entries []worktreeEntry
+	changes int
+	valid   bool
+}
+
+// listWorktrees returns the worktrees git knows about, main worktree first.
+// The porcelain list is read once per command and reused until wtgo runs a
+// git command that may change it, so callers can look worktrees up freely.
+// The returned slice is shared and must not be modified.
+func listWorktrees() ([]worktreeEntry, error) {
+	if worktreeList.valid && worktreeList.changes == git.Changes() {
+		return worktreeList.entries, nil
+	}
+
+	// Without -z, git prints paths verbatim, so one containing a newline
+	// would split into two lines.
+	args := []string{"worktree", "list", "--porcelain"}
+	separator := "\n"
+	if git.VersionAtLeast(2, 36) {
+		args = append(args, "-z")
+		separator = "\x00"
+	}
+	output, err := git.Exec(args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list worktrees: %w", err)
+	}
+	worktreeList.entries = parseWorktreeList(output, separator)
+	worktreeList.changes = git.Changes()
+	worktreeList.valid = true
+	return worktreeList.entries, nil
+}
+
+// parseWorktreeList parses the output of `git worktree list --porcelain`,
+// whose lines end with separator: a newline, or a NUL byte with -z. Each
+// `worktree` line starts a new entry, so a missing blank line between
+// entries cannot carry one entry's attributes over to the next.
+func parseWorktreeList(output, separator string) []worktreeEntry {
+	var entries []worktreeEntry
+	for _, line := range strings.Split(output, separator) {
+		line = strings.TrimSuffix(line, "\r")
+		if path, ok := parseWorktreeLine(line); ok {
+			entries = append(entries, worktreeEntry{Path: path})
 			continue
 		}
-
-		if strings.HasPrefix(line, "worktree ") {
-			currentPath = strings.TrimPrefix(line, "worktree ")
-		} else if strings.HasPrefix(line, "branch ") {
-			parts := strings.SplitN(line, " ", 2)
-			if len(parts) == 2 {
-				branchRef := strings.TrimPrefix(parts[1], "refs/heads/")
-				currentBranch = branchRef
+		if len(entries) == 0 {
+			continue
+		}
+		entry := &entries[len(entries)-1]
+		switch {
+		case line == "bare":
+			entry.Bare = true
+		case line == "detached":
+			entry.Detached = true
+		case line == "prunable" || strings.HasPrefix(line, "prunable "):
+			entry.Prunable = true
+		default:
+			if head, ok := strings.CutPrefix(line, "HEAD "); ok {
+				entry.Head = head
+			} else if branch, ok := parseBranchLine(line); ok {
+				entry.Branch = branch
+			} else if reason, ok := parseLockedLine(line); ok {
+				entry.Locked = true
+				entry.LockReason = reason
 			}
 		}
 	}
+	return entries
+}
+
+// parseWorktreeLine extracts the path from a porcelain `worktree <path>` line.
+// Git prints forward slashes even on Windows, so the path is converted to
+// the native separator to compare correctly with paths from the OS.
+func parseWorktreeLine(line string) (string, bool) {
+	path, ok := strings.CutPrefix(line, "worktree ")
+	if !ok {
+		return "", false
+	}
+	return filepath.FromSlash(path), true
+}
 
-	if currentPath != "" && currentBranch == branchName {
-		return currentPath, nil
+// parseBranchLine extracts the local branch name from a porcelain
+// `branch refs/heads/<name>` line. Only the leading `refs/heads/` is stripped,
+// so a branch that is itself named `refs/...` keeps its full name.
+// It reports false for any other line, including refs outside refs/heads.
+func parseBranchLine(line string) (string, bool) {
+	ref, ok := strings.CutPrefix(line, "branch ")
+	if !ok {
+		return "", false
+	}
+	branch, ok := strings.CutPrefix(ref, "refs/heads/")
+	if !ok || branch == "" {
+		return "", false
 	}
+	return branch, true
+}
 
-	return "", nil
+// WorktreeInfo is the JSON form of a ListWorktrees entry.
+type WorktreeInfo struct {
+	Branch     string `json:"branch"`
+	Path       string `json:"path"`
+	Head       string `json:"head"`
+	IsCurrent  bool   `json:"isCurrent"`
+	Issue      string `json:"issue,omitempty"`
+	Pinned     bool   `json:"pinned"`
+	Locked     bool   `json:"locked"`
+	LockReason string `json:"lockReason,omitempty"`
 }
 
-// ListWorktrees lists all existing Git worktrees.
+// ListWorktrees lists all existing Git worktrees and returns how many were listed.
 // It parses the output of `git worktree list --porcelain` to display only branch names.
-func ListWorktrees() {
-	output, err := git.Exec("worktree", "list", "--porcelain")
+// With lockedOnly set, only worktrees locked by git are listed.
+// Branches matched by the .wtgoignore file are left out unless ListIgnored is set.
+// With ListAge set, the oldest branches come first.
+// Each branch is printed on its own newline-terminated line after a header.
+// With ListPaths set, there is no header and each line is the branch and its
+// path separated by a tab. With Output.Print0 set, only the bare branch names
+// (or branch-tab-path pairs) are printed, each terminated by a NUL byte.
+// With JSONOutput set, it prints a JSON array of WorktreeInfo instead, which
+// is `[]` when there are none.
+func ListWorktrees(lockedOnly bool) (int, error) {
+	entries, err := listWorktrees()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error listing worktrees: %v\n", err)
-		return
+		return 0, err
 	}
 
 	var orderedBranchNames []string
 	seenBranches := make(map[string]bool)
-	lines := strings.Split(output, "\n")
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "branch ") {
-			parts := strings.SplitN(line, " ", 2)
-			if len(parts) == 2 { // Ensure there's a branch ref part
-				branchRef := strings.TrimPrefix(parts[1], "refs/heads/")
-				if branchRef != "" {
-					if !seenBranches[branchRef] {
-						orderedBranchNames = append(orderedBranchNames, branchRef)
-						seenBranches[branchRef] = true
-					}
-				}
+	branchPaths := make(map[string]string)
+
+	lockedPaths := make(map[string]bool)
+	lockReasons := make(map[string]string)
+	heads := make(map[string]string)
+
+	for _, entry := range entries {
+		if entry.Locked {
+			lockedPaths[entry.Path] = true
+			lockReasons[entry.Path] = entry.LockReason
+		}
+		heads[entry.Path] = entry.Head
+		if entry.Branch != "" && !seenBranches[entry.Branch] {
+			orderedBranchNames = append(orderedBranchNames, entry.Branch)
+			seenBranches[entry.Branch] = true
+			branchPaths[entry.Branch] = entry.Path
+		}
+	}
+
+	if !ListIgnored {
+		patterns, err := ignoredBranchPatterns()
+		if err != nil {
+			log.Warnf("%v", err)
+		}
+		var shown []string
+		for _, branch := range orderedBranchNames {
+			if !isIgnoredBranch(branch, patterns) {
+				shown = append(shown, branch)
+			}
+		}
+		orderedBranchNames = shown
+	}
+
+	if lockedOnly {
+		var lockedBranches []string
+		for _, branch := range orderedBranchNames {
+			if lockedPaths[branchPaths[branch]] {
+				lockedBranches = append(lockedBranches, branch)
 			}
 		}
+		orderedBranchNames = lockedBranches
+	}
+
+	var ages map[string]string
+	if ListAge {
+		var byAge []string
+		if byAge, ages, err = commitAges(); err != nil {
+			log.Warnf("could not read branch commit dates: %v", err)
+		}
+		rank := make(map[string]int, len(byAge))
+		for i, branch := range byAge {
+			rank[branch] = i
+		}
+		slices.SortStableFunc(orderedBranchNames, func(a, b string) int {
+			return cmp.Compare(rank[a], rank[b])
+		})
 	}
 
-	if len(orderedBranchNames) == 0 {
+	if len(orderedBranchNames) == 0 && !JSONOutput && !Output.Print0 && !ListPaths {
+		if lockedOnly {
+			fmt.Fprintln(os.Stdout, "No locked Git worktrees found.")
+			return 0, nil
+		}
 		fmt.Fprintln(os.Stdout, "No Git worktrees found.")
-		return
+		return 0, nil
+	}
+
+	pinned, err := pinnedPaths()
+	if err != nil {
+		log.Warnf("could not read pinned worktrees: %v", err)
+	}
+
+	issues, err := branchIssues()
+	if err != nil {
+		log.Warnf("could not read branch issues: %v", err)
+	}
+
+	if JSONOutput {
+		var currentTop string
+		if top, err := git.Exec("rev-parse", "--show-toplevel"); err == nil {
+			currentTop = canonicalPath(filepath.FromSlash(strings.TrimSpace(top)))
+		}
+
+		entries := make([]WorktreeInfo, 0, len(orderedBranchNames))
+		for _, branch := range orderedBranchNames {
+			path := branchPaths[branch]
+			entries = append(entries, WorktreeInfo{
+				Branch:     branch,
+				Path:       path,
+				Head:       heads[path],
+				IsCurrent:  currentTop != "" && canonicalPath(path) == currentTop,
+				Issue:      issues[branch],
+				Pinned:     pinned[canonicalPath(path)],
+				Locked:     lockedPaths[path],
+				LockReason: lockReasons[path],
+			})
+		}
+		data, err := json.Marshal(entries)
+		if err != nil {
+			return 0, fmt.Errorf("encoding worktrees: %w", err)
+		}
+		fmt.Println(string(data))
+		return len(entries), nil
+	}
+
+	if ListPaths {
+		terminator := "\n"
+		if Output.Print0 {
+			terminator = "\x00"
+		}
+		for _, branch := range orderedBranchNames {
+			fmt.Print(branch + "\t" + branchPaths[branch] + terminator)
+		}
+		return len(orderedBranchNames), nil
+	}
+
+	if Output.Print0 {
+		for _, branch := range orderedBranchNames {
+			fmt.Print(branch + "\x00")
+		}
+		return len(orderedBranchNames), nil
+	}
+
+	var tracks map[string]string
+	if ListTracking {
+		if tracks, err = upstreamTracks(); err != nil {
+			log.Warnf("could not compare branches with their upstreams: %v", err)
+		}
 	}
 
 	fmt.Fprintln(os.Stdout, "Git worktree branches:")
 	for _, branch := range orderedBranchNames {
-		fmt.Println(branch)
+		line := branch
+		if track := tracks[branch]; track != "" {
+			line += " " + track
+		}
+		if age := ages[branch]; age != "" {
+			line += " (committed " + age + ")"
+		}
+		if issue := issues[branch]; issue != "" {
+			line += " [" + issue + "]"
+		}
+		if pinned[canonicalPath(branchPaths[branch])] {
+			line += " (pinned)"
+		}
+		if path := branchPaths[branch]; lockedPaths[path] {
+			if reason := lockReasons[path]; reason != "" {
+				line += " (locked: " + reason + ")"
+			} else {
+				line += " (locked)"
+			}
+		}
+		fmt.Println(line)
 	}
+	return len(orderedBranchNames), nil
 }
 
-// SwitchToPreviousWorktree returns the path of the previous worktree from the state file.
-// It also saves the current working directory to the state file to allow toggling.
-func SwitchToPreviousWorktree() (string, error) {
-	stateFile, err := getStateFilePath()
+// SwitchToPreviousWorktree returns the path n steps back in the worktree
+// history, where 1 is the most recent entry. The entry is taken off the
+// history and the current working directory is pushed in its place, so
+// repeated `wtgo -` toggles between two worktrees.
+func SwitchToPreviousWorktree(n int) (string, error) {
+	unlock, err := lockRepo()
 	if err != nil {
-		return "", fmt.Errorf("getting state file path: %w", err)
+		return "", err
 	}
+	defer unlock()
 
-	content, err := os.ReadFile(stateFile)
+	history, err := readHistory()
 	if err != nil {
-		if os.IsNotExist(err) {
-			return "", fmt.Errorf("no previous worktree state found")
-		}
-		return "", fmt.Errorf("reading state file: %w", err)
+		return "", err
 	}
-
-	path := strings.TrimSpace(string(content))
-	if path == "" {
-		return "", fmt.Errorf("state file is empty")
+	if len(history) == 0 {
+		return "", fmt.Errorf("no previous worktree state found")
+	}
+	if n < 1 || n > len(history) {
+		return "", fmt.Errorf("cannot go back %d; the worktree history has %d entries", n, len(history))
 	}
 
-	// Before returning the path to switch to, we should save the current path.
-	// This allows for toggling between two worktrees with `wt -`.
-	if err := saveCurrentWorktreeState(); err != nil {
+	path := history[n-1]
+	history = append(history[:n-1:n-1], history[n:]...)
+
+	inRepo, err := belongsToRepo(path)
+	if err != nil {
+		return "", err
+	}
+	// Push the current path either way: it lets `wtgo -` toggle back, and it
+	// replaces a foreign entry so the next `wtgo -` works again.
+	if err := pushHistory(history); err != nil {
 		// Not a fatal error for switching, but the user should know.
-		fmt.Fprintf(os.Stderr, "Warning: could not save current worktree state: %v\n", err)
+		log.Warnf("could not save current worktree state: %v", err)
+	}
+	if !inRepo {
+		return "", fmt.Errorf("%w: '%s' is not inside any worktree of this repository; it has been dropped from the history", ErrForeignState, path)
 	}
 
 	return path, nil
 }
 
+// ErrForeignState is returned when the state file names a path that does not
+// belong to the current repository, e.g. because it was copied from another.
+var ErrForeignState = errors.New("previous worktree belongs to another repository")
+
+// belongsToRepo reports whether path lies inside one of the repository's
+// worktrees or inside its collection directory.
+func belongsToRepo(path string) (bool, error) {
+	known, err := worktreePaths()
+	if err != nil {
+		return false, err
+	}
+	roots := make([]string, 0, len(known)+1)
+	for root := range known {
+		roots = append(roots, root)
+	}
+	if dir, err := collectionDir(); err == nil {
+		roots = append(roots, canonicalPath(dir))
+	}
+
+	path = canonicalPath(path)
+	for _, root := range roots {
+		if path == root || strings.HasPrefix(path, root+string(filepath.Separator)) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 func getStateFilePath() (string, error) {
+	return commonDirFile("wt.state")
+}
+
+func getPinnedFilePath() (string, error) {
+	return commonDirFile("wt.pinned")
+}
+
+func getIssuesFilePath() (string, error) {
+	return commonDirFile("wt.issues")
+}
+
+func getLastRemovedFilePath() (string, error) {
+	return commonDirFile("wt.last-removed")
+}
+
+func getLockFilePath() (string, error) {
+	return commonDirFile("wt.lock")
+}
+
+// commonDirFile returns the path of a wtgo-owned file inside the git common
+// directory, which is shared by all worktrees of the repository.
+func commonDirFile(name string) (string, error) {
 	gitCommonDir, err := git.Exec("rev-parse", "--git-common-dir")
 	if err != nil {
-		return "", fmt.Errorf("not a git repository or could not determine common git directory: %w", err)
+		return "", fmt.Errorf("%w or could not determine common git directory: %w", ErrNotGitRepo, err)
 	}
-	gitCommonDir = strings.TrimSpace(gitCommonDir)
+	gitCommonDir = filepath.FromSlash(strings.TrimSpace(gitCommonDir))
 
-	return filepath.Join(gitCommonDir, "wt.state"), nil
+	return filepath.Join(gitCommonDir, name), nil
 }
 
-func saveCurrentWorktreeState() error {
+// maxHistory caps the number of entries kept in the worktree history.
+const maxHistory = 20
+
+// readHistory returns the worktree history from the state file, most recent
+// first. A missing state file is an empty history. Entries are converted to
+// the native separator, so one written with forward slashes, e.g. from Git
+// Bash on Windows, still matches the current directory.
+func readHistory() ([]string, error) {
+	stateFile, err := getStateFilePath()
+	if err != nil {
+		return nil, fmt.Errorf("getting state file path: %w", err)
+	}
+
+	content, err := os.ReadFile(stateFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading state file: %w", err)
+	}
+
+	var history []string
+	for _, line := range strings.Split(string(content), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			history = append(history, filepath.FromSlash(line))
+		}
+	}
+	return history, nil
+}
+
+// pushHistory writes history to the state file with the current working
+// directory on top, unless it is already the most recent entry.
+func pushHistory(history []string) error {
 	stateFile, err := getStateFilePath()
 	if err != nil {
 		return fmt.Errorf("could not get state file path: %w", err)
@@ -295,14 +1351,52 @@ func saveCurrentWorktreeState() error {
 		return fmt.Errorf("could not get current working directory: %w", err)
 	}
 
-	content, err := os.ReadFile(stateFile)
-	if err == nil {
-		if strings.TrimSpace(string(content)) == wd {
-			return nil // Path is the same, no need to update.
+	if len(history) == 0 || history[0] != wd {
+		history = append([]string{wd}, history...)
+	}
+	if len(history) > maxHistory {
+		history = history[:maxHistory]
+	}
+	return writeFileAtomic(stateFile, []byte(strings.Join(history, "\n")+"\n"), 0644)
+}
+
+// moveHistoryEntries rewrites the entries of the worktree history at or
+// below oldPath to point at newPath instead.
+func moveHistoryEntries(oldPath, newPath string) error {
+	history, err := readHistory()
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	for i, entry := range history {
+		if entry == oldPath {
+			history[i] = newPath
+			changed = true
+		} else if rest, ok := strings.CutPrefix(entry, oldPath+string(filepath.Separator)); ok {
+			history[i] = filepath.Join(newPath, rest)
+			changed = true
 		}
-	} else if !os.IsNotExist(err) {
-		return fmt.Errorf("could not read state file for comparison: %w", err)
 	}
+	if !changed {
+		return nil
+	}
+
+	stateFile, err := getStateFilePath()
+	if err != nil {
+		return fmt.Errorf("could not get state file path: %w", err)
+	}
+	return writeFileAtomic(stateFile, []byte(strings.Join(history, "\n")+"\n"), 0644)
+}
 
-	return os.WriteFile(stateFile, []byte(wd), 0644)
+// saveCurrentWorktreeState pushes the current working directory onto the
+// worktree history. Like every read-modify-write of the state file, it must
+// run under lockRepo; the write itself is atomic, so readers never see a
+// partial file.
+func saveCurrentWorktreeState() error {
+	history, err := readHistory()
+	if err != nil {
+		return err
+	}
+	return pushHistory(history)
 }
@@ -0,0 +1,679 @@
+package worktree
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newTestRepo creates a git repository with one commit on main in a
+// temporary directory and returns its path. The environment is set up for
+// the rest of the test so neither the user's git config nor their wtgo
+// settings interfere, and cached repository state is dropped. The test is
+// skipped if git is not installed.
+func newTestRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	for name, value := range map[string]string{
+		"GIT_CONFIG_NOSYSTEM": "1",
+		"GIT_CONFIG_GLOBAL":   os.DevNull,
+		"GIT_AUTHOR_NAME":     "wtgo",
+		"GIT_AUTHOR_EMAIL":    "wtgo@example.com",
+		"GIT_COMMITTER_NAME":  "wtgo",
+		"GIT_COMMITTER_EMAIL": "wtgo@example.com",
+		"WTGO_WORKTREE_DIR":   "",
+		"WTGO_GLOBAL_ROOT":    "",
+		"WTGO_POST_CREATE":    "",
+	} {
+		t.Setenv(name, value)
+	}
+	// The cached worktree list belongs to whichever repository was current.
+	worktreeList.valid = false
+	t.Cleanup(func() { worktreeList.valid = false })
+
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := filepath.Join(dir, "repo")
+	runGit(t, dir, "init", "--quiet", "--initial-branch=main", repo)
+	runGit(t, repo, "commit", "--quiet", "--allow-empty", "-m", "initial")
+	return repo
+}
+
+// runGit runs git with args in dir, failing the test if it fails, and
+// returns its trimmed output.
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// porcelainZ joins porcelain records the way `git worktree list --porcelain
+// -z` prints them: every line ends in NUL and every record in an extra NUL.
+func porcelainZ(records ...[]string) string {
+	var b strings.Builder
+	for _, record := range records {
+		for _, line := range record {
+			b.WriteString(line)
+			b.WriteByte(0)
+		}
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+func TestParseWorktreeList(t *testing.T) {
+	const (
+		head1 = "1111111111111111111111111111111111111111"
+		head2 = "2222222222222222222222222222222222222222"
+	)
+
+	tests := []struct {
+		name   string
+		output string
+		want   []worktreeEntry
+	}{
+		{
+			name:   "empty",
+			output: "",
+			want:   nil,
+		},
+		{
+			name: "main and linked worktree",
+			output: porcelainZ(
+				[]string{"worktree /repo", "HEAD " + head1, "branch refs/heads/main"},
+				[]string{"worktree /repo.wt/feat", "HEAD " + head2, "branch refs/heads/feat/x"},
+			),
+			want: []worktreeEntry{
+				{Path: filepath.FromSlash("/repo"), Head: head1, Branch: "main"},
+				{Path: filepath.FromSlash("/repo.wt/feat"), Head: head2, Branch: "feat/x"},
+			},
+		},
+		{
+			name:   "bare",
+			output: porcelainZ([]string{"worktree /repo.git", "bare"}),
+			want:   []worktreeEntry{{Path: filepath.FromSlash("/repo.git"), Bare: true}},
+		},
+		{
+			name:   "detached",
+			output: porcelainZ([]string{"worktree /repo.wt/d", "HEAD " + head1, "detached"}),
+			want:   []worktreeEntry{{Path: filepath.FromSlash("/repo.wt/d"), Head: head1, Detached: true}},
+		},
+		{
+			name: "locked with and without reason",
+			output: porcelainZ(
+				[]string{"worktree /repo.wt/a", "HEAD " + head1, "branch refs/heads/a", "locked"},
+				[]string{"worktree /repo.wt/b", "HEAD " + head1, "branch refs/heads/b", "locked on a usb\ndrive"},
+			),
+			want: []worktreeEntry{
+				{Path: filepath.FromSlash("/repo.wt/a"), Head: head1, Branch: "a", Locked: true},
+				{Path: filepath.FromSlash("/repo.wt/b"), Head: head1, Branch: "b", Locked: true, LockReason: "on a usb\ndrive"},
+			},
+		},
+		{
+			name: "prunable with and without reason",
+			output: porcelainZ(
+				[]string{"worktree /repo.wt/a", "HEAD " + head1, "branch refs/heads/a", "prunable"},
+				[]string{"worktree /repo.wt/b", "HEAD " + head1, "branch refs/heads/b", "prunable gitdir file points to non-existent location"},
+			),
+			want: []worktreeEntry{
+				{Path: filepath.FromSlash("/repo.wt/a"), Head: head1, Branch: "a", Prunable: true},
+				{Path: filepath.FromSlash("/repo.wt/b"), Head: head1, Branch: "b", Prunable: true},
+			},
+		},
+		{
+			name: "locked and prunable",
+			output: porcelainZ(
+				[]string{"worktree /repo.wt/a", "HEAD " + head1, "branch refs/heads/a", "locked usb", "prunable gone"},
+			),
+			want: []worktreeEntry{
+				{Path: filepath.FromSlash("/repo.wt/a"), Head: head1, Branch: "a", Locked: true, LockReason: "usb", Prunable: true},
+			},
+		},
+		{
+			name: "path with newline",
+			output: porcelainZ(
+				[]string{"worktree /repo.wt/two\nlines", "HEAD " + head1, "branch refs/heads/two"},
+			),
+			want: []worktreeEntry{
+				{Path: filepath.FromSlash("/repo.wt/two\nlines"), Head: head1, Branch: "two"},
+			},
+		},
+		{
+			name: "path with spaces and unicode",
+			output: porcelainZ(
+				[]string{"worktree /répo.wt/日本 語", "HEAD " + head1, "branch refs/heads/ünï"},
+			),
+			want: []worktreeEntry{
+				{Path: filepath.FromSlash("/répo.wt/日本 語"), Head: head1, Branch: "ünï"},
+			},
+		},
+		{
+			name: "branch named refs/...",
+			output: porcelainZ(
+				[]string{"worktree /repo.wt/r", "HEAD " + head1, "branch refs/heads/refs/heads/x"},
+			),
+			want: []worktreeEntry{
+				{Path: filepath.FromSlash("/repo.wt/r"), Head: head1, Branch: "refs/heads/x"},
+			},
+		},
+		{
+			name: "unknown attributes are ignored",
+			output: porcelainZ(
+				[]string{"worktree /repo", "HEAD " + head1, "branch refs/heads/main", "frobnicated yes"},
+			),
+			want: []worktreeEntry{
+				{Path: filepath.FromSlash("/repo"), Head: head1, Branch: "main"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseWorktreeList(tt.output, "\x00")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseWorktreeList() =\n%#v\nwant\n%#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseWorktreeListNewlineSeparated(t *testing.T) {
+	output := "worktree /repo\r\nHEAD abc\r\nbranch refs/heads/main\r\n\r\n" +
+		"worktree /repo.wt/d\nHEAD def\ndetached\nlocked\n\n"
+	want := []worktreeEntry{
+		{Path: filepath.FromSlash("/repo"), Head: "abc", Branch: "main"},
+		{Path: filepath.FromSlash("/repo.wt/d"), Head: "def", Detached: true, Locked: true},
+	}
+	if got := parseWorktreeList(output, "\n"); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseWorktreeList() =\n%#v\nwant\n%#v", got, want)
+	}
+}
+
+// captureOutput runs f with os.Stdout and os.Stderr redirected to pipes and
+// returns what was written to each.
+func captureOutput(t *testing.T, f func()) (stdout, stderr string) {
+	t.Helper()
+	read := func(target **os.File) (restore func() string) {
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		saved := *target
+		*target = w
+		done := make(chan string)
+		go func() {
+			data, _ := io.ReadAll(r)
+			r.Close()
+			done <- string(data)
+		}()
+		return func() string {
+			*target = saved
+			w.Close()
+			return <-done
+		}
+	}
+
+	restoreStdout := read(&os.Stdout)
+	restoreStderr := read(&os.Stderr)
+	defer func() {
+		stdout = restoreStdout()
+		stderr = restoreStderr()
+	}()
+	f()
+	return
+}
+
+func TestCreateWorktreeAndBranchStdoutIsOnlyThePath(t *testing.T) {
+	repo := newTestRepo(t)
+	t.Chdir(repo)
+	saved := Output
+	Output = OutputOptions{}
+	t.Cleanup(func() { Output = saved })
+
+	var err error
+	stdout, stderr := captureOutput(t, func() {
+		err = CreateWorktreeAndBranch("feat/x", CreateOptions{})
+	})
+	if err != nil {
+		t.Fatalf("CreateWorktreeAndBranch: %v\nstderr:\n%s", err, stderr)
+	}
+
+	want := filepath.Join(filepath.Dir(repo), "repo.wt", "feat_x")
+	if stdout != want {
+		t.Errorf("stdout = %q, want exactly %q", stdout, want)
+	}
+	if !strings.Contains(stderr, "Preparing worktree") {
+		t.Errorf("git's output did not go to stderr; stderr = %q", stderr)
+	}
+}
+
+func TestSwitchToPreviousWorktree(t *testing.T) {
+	repo := newTestRepo(t)
+	t.Chdir(repo)
+	var worktrees []string
+	for _, branch := range []string{"a", "b", "c"} {
+		path := filepath.Join(filepath.Dir(repo), "repo.wt", branch)
+		runGit(t, repo, "worktree", "add", "--quiet", "-b", branch, path)
+		worktrees = append(worktrees, path)
+	}
+	stateFile := filepath.Join(repo, ".git", "wt.state")
+
+	tests := []struct {
+		name        string
+		history     []string // nil leaves no state file
+		n           int
+		want        string
+		wantErr     string
+		wantHistory []string
+	}{
+		{
+			name:    "empty stack",
+			n:       1,
+			wantErr: "no previous worktree state found",
+		},
+		{
+			name:    "empty state file",
+			history: []string{},
+			n:       1,
+			wantErr: "no previous worktree state found",
+		},
+		{
+			name:        "dash",
+			history:     worktrees,
+			n:           1,
+			want:        worktrees[0],
+			wantHistory: []string{repo, worktrees[1], worktrees[2]},
+		},
+		{
+			name:        "steps back",
+			history:     worktrees,
+			n:           3,
+			want:        worktrees[2],
+			wantHistory: []string{repo, worktrees[0], worktrees[1]},
+		},
+		{
+			name:        "out of range",
+			history:     worktrees,
+			n:           4,
+			wantErr:     "cannot go back 4; the worktree history has 3 entries",
+			wantHistory: worktrees,
+		},
+		{
+			name:        "zero steps",
+			history:     worktrees,
+			n:           0,
+			wantErr:     "cannot go back 0",
+			wantHistory: worktrees,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Remove(stateFile)
+			if tt.history != nil {
+				content := strings.Join(tt.history, "\n") + "\n"
+				if err := os.WriteFile(stateFile, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := SwitchToPreviousWorktree(tt.n)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("SwitchToPreviousWorktree(%d) error = %v, want one containing %q", tt.n, err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("SwitchToPreviousWorktree(%d): %v", tt.n, err)
+			} else if got != tt.want {
+				t.Errorf("SwitchToPreviousWorktree(%d) = %q, want %q", tt.n, got, tt.want)
+			}
+
+			history, err := readHistory()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(history, tt.wantHistory) {
+				t.Errorf("history afterwards = %q, want %q", history, tt.wantHistory)
+			}
+		})
+	}
+}
+
+func TestPushHistory(t *testing.T) {
+	repo := newTestRepo(t)
+	t.Chdir(repo)
+
+	a, b := filepath.FromSlash("/a"), filepath.FromSlash("/b")
+	long := make([]string, maxHistory)
+	for i := range long {
+		long[i] = filepath.FromSlash(fmt.Sprintf("/entry/%d", i))
+	}
+
+	tests := []struct {
+		name    string
+		history []string
+		want    []string
+	}{
+		{"empty", nil, []string{repo}},
+		{"pushed on top", []string{a, b}, []string{repo, a, b}},
+		{"consecutive duplicate", []string{repo, a}, []string{repo, a}},
+		{"earlier duplicate kept", []string{a, repo}, []string{repo, a, repo}},
+		{"capped", long, append([]string{repo}, long[:maxHistory-1]...)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := pushHistory(tt.history); err != nil {
+				t.Fatal(err)
+			}
+			got, err := readHistory()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("history = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBranchLine(t *testing.T) {
+	tests := []struct {
+		line   string
+		want   string
+		wantOK bool
+	}{
+		{"branch refs/heads/main", "main", true},
+		{"branch refs/heads/feat/x", "feat/x", true},
+		{"branch refs/heads/refs/heads/x", "refs/heads/x", true},
+		{"branch refs/heads/refs/tags/v1", "refs/tags/v1", true},
+		{"branch refs/heads/ünï", "ünï", true},
+		{"branch refs/heads/", "", false},
+		{"branch refs/remotes/origin/main", "", false},
+		{"branch main", "", false},
+		{"branch ", "", false},
+		{"branch", "", false},
+		{"HEAD refs/heads/main", "", false},
+		{"worktree /repo", "", false},
+		{"detached", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, ok := parseBranchLine(tt.line)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("parseBranchLine(%q) = %q, %v; want %q, %v", tt.line, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestFindWorktreePathForBranchBareAndDetached(t *testing.T) {
+	repo := newTestRepo(t)
+	dir := filepath.Dir(repo)
+	bare := filepath.Join(dir, "bare.git")
+	runGit(t, dir, "clone", "--quiet", "--bare", repo, bare)
+	feature := filepath.Join(dir, "bare.wt", "feature")
+	detached := filepath.Join(dir, "bare.wt", "detached")
+	runGit(t, bare, "worktree", "add", "--quiet", "-b", "feature", feature)
+	runGit(t, bare, "worktree", "add", "--quiet", "--detach", detached)
+	t.Chdir(bare)
+
+	tests := []struct {
+		branch string
+		want   string
+	}{
+		{"feature", feature},
+		// main exists in the bare repository, but is not checked out.
+		{"main", ""},
+		{"", ""},
+		{"missing", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.branch, func(t *testing.T) {
+			got, err := FindWorktreePathForBranch(tt.branch)
+			if err != nil {
+				t.Fatalf("FindWorktreePathForBranch(%q): %v", tt.branch, err)
+			}
+			if got != tt.want {
+				t.Errorf("FindWorktreePathForBranch(%q) = %q, want %q", tt.branch, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindWorktreePathForBranchUnusualNames(t *testing.T) {
+	repo := newTestRepo(t)
+	wtDir := filepath.Join(filepath.Dir(repo), "repo.wt")
+	worktrees := map[string]string{
+		"feat/x":       filepath.Join(wtDir, "feat_x"),
+		"ünï/ßranch":   filepath.Join(wtDir, "unicode"),
+		"refs/heads/x": filepath.Join(wtDir, "refs_heads_x"),
+	}
+	for branch, path := range worktrees {
+		runGit(t, repo, "worktree", "add", "--quiet", "-b", branch, path)
+	}
+	t.Chdir(repo)
+
+	tests := []struct {
+		branch string
+		want   string
+	}{
+		{"feat/x", worktrees["feat/x"]},
+		{"ünï/ßranch", worktrees["ünï/ßranch"]},
+		{"refs/heads/x", worktrees["refs/heads/x"]},
+		// Neither the short name nor the full ref of the branch named
+		// refs/heads/x resolves to its worktree.
+		{"x", ""},
+		{"refs/heads/refs/heads/x", ""},
+		{"feat", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.branch, func(t *testing.T) {
+			got, err := FindWorktreePathForBranch(tt.branch)
+			if err != nil {
+				t.Fatalf("FindWorktreePathForBranch(%q): %v", tt.branch, err)
+			}
+			if got != tt.want {
+				t.Errorf("FindWorktreePathForBranch(%q) = %q, want %q", tt.branch, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindWorktreePathForBranchMainWorktree(t *testing.T) {
+	repo := newTestRepo(t)
+	feature := filepath.Join(filepath.Dir(repo), "repo.wt", "feature")
+	runGit(t, repo, "worktree", "add", "--quiet", "-b", "feature", feature)
+
+	// The answer must not depend on which worktree asks.
+	for _, wd := range []string{repo, feature, filepath.Join(repo, ".git")} {
+		t.Run(filepath.Base(wd), func(t *testing.T) {
+			t.Chdir(wd)
+			worktreeList.valid = false
+			for branch, want := range map[string]string{"main": repo, "feature": feature} {
+				got, err := FindWorktreePathForBranch(branch)
+				if err != nil {
+					t.Fatalf("FindWorktreePathForBranch(%q): %v", branch, err)
+				}
+				if got != want {
+					t.Errorf("FindWorktreePathForBranch(%q) = %q, want %q", branch, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateWorktreeSwitchesToMainWorktree(t *testing.T) {
+	repo := newTestRepo(t)
+	feature := filepath.Join(filepath.Dir(repo), "repo.wt", "feature")
+	runGit(t, repo, "worktree", "add", "--quiet", "-b", "feature", feature)
+	t.Chdir(feature)
+
+	// `wtgo main` from a linked worktree points at the root checkout.
+	got, err := CreateWorktree("main", CreateOptions{NoSwitch: true})
+	if err != nil {
+		t.Fatalf("CreateWorktree(main): %v", err)
+	}
+	if got != repo {
+		t.Errorf("CreateWorktree(main) = %q, want the main worktree %q", got, repo)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(repo), "repo.wt", "main")); !os.IsNotExist(err) {
+		t.Errorf("a second worktree for main was created (stat error %v)", err)
+	}
+}
+
+// onWindows picks the expectation for the platform the test runs on, for
+// paths git prints with forward slashes even on Windows.
+func onWindows(windows, other string) string {
+	if filepath.Separator == '\\' {
+		return windows
+	}
+	return other
+}
+
+func TestParseWorktreeLineWindowsPaths(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"worktree C:/Users/dev/repo", onWindows(`C:\Users\dev\repo`, "C:/Users/dev/repo")},
+		{"worktree C:/Users/dev/repo.wt/feat_x", onWindows(`C:\Users\dev\repo.wt\feat_x`, "C:/Users/dev/repo.wt/feat_x")},
+		{"worktree D:/", onWindows(`D:\`, "D:/")},
+		{"worktree //server/share/repo", onWindows(`\\server\share\repo`, "//server/share/repo")},
+		// Backslashes are left alone everywhere: on Windows they already are
+		// separators, elsewhere they are part of the name.
+		{`worktree C:\Users\dev\repo`, `C:\Users\dev\repo`},
+		{"worktree C:/Program Files/repo", onWindows(`C:\Program Files\repo`, "C:/Program Files/repo")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, ok := parseWorktreeLine(tt.line)
+			if !ok || got != tt.want {
+				t.Errorf("parseWorktreeLine(%q) = %q, %v; want %q, true", tt.line, got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseWorktreeListDriveLetters(t *testing.T) {
+	const head = "1111111111111111111111111111111111111111"
+	output := porcelainZ(
+		[]string{"worktree C:/src/repo", "HEAD " + head, "branch refs/heads/main"},
+		[]string{"worktree C:/src/repo.wt/feat_x", "HEAD " + head, "branch refs/heads/feat/x"},
+	)
+	want := []worktreeEntry{
+		{Path: onWindows(`C:\src\repo`, "C:/src/repo"), Head: head, Branch: "main"},
+		{Path: onWindows(`C:\src\repo.wt\feat_x`, "C:/src/repo.wt/feat_x"), Head: head, Branch: "feat/x"},
+	}
+	if got := parseWorktreeList(output, "\x00"); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseWorktreeList = %+v, want %+v", got, want)
+	}
+}
+
+func TestWorktreePathInWindowsCharacters(t *testing.T) {
+	repo := newTestRepo(t)
+	t.Chdir(repo)
+	t.Setenv("WTGO_SANITIZE", "")
+	t.Setenv("WTGO_NAME_TEMPLATE", "")
+	dir := filepath.FromSlash("C:/src/repo.wt")
+
+	tests := []struct {
+		branch string
+		want   string
+	}{
+		{"feat/x", "feat_x"},
+		{"a<b>c", onWindows("a_b_c", "a<b>c")},
+		{`say-"hi"`, onWindows("say-_hi_", `say-"hi"`)},
+		{"feat/a|b", onWindows("feat_a_b", "feat_a|b")},
+		{"ünï/x", "ünï_x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.branch, func(t *testing.T) {
+			got, err := worktreePathIn(dir, tt.branch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := filepath.Join(dir, tt.want); got != want {
+				t.Errorf("worktreePathIn(%q) = %q, want %q", tt.branch, got, want)
+			}
+		})
+	}
+}
+
+// TestSwitchToPreviousWorktreeSlashHistory goes back to an entry written with
+// forward slashes, as by a shell like Git Bash, which on Windows differs from
+// the native path it names.
+func TestSwitchToPreviousWorktreeSlashHistory(t *testing.T) {
+	repo := newTestRepo(t)
+	t.Chdir(repo)
+	path := filepath.Join(filepath.Dir(repo), "repo.wt", "a")
+	runGit(t, repo, "worktree", "add", "--quiet", "-b", "a", path)
+	stateFile := filepath.Join(repo, ".git", "wt.state")
+	content := filepath.ToSlash(path) + "\n" + filepath.ToSlash(repo) + "\n"
+	if err := os.WriteFile(stateFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := SwitchToPreviousWorktree(1)
+	if err != nil {
+		t.Fatalf("SwitchToPreviousWorktree(1): %v", err)
+	}
+	if got != path {
+		t.Errorf("SwitchToPreviousWorktree(1) = %q, want %q", got, path)
+	}
+
+	// The current directory matches the remaining entry once both use the
+	// native separator, so it is not pushed twice.
+	history, err := readHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{repo}; !reflect.DeepEqual(history, want) {
+		t.Errorf("history afterwards = %q, want %q", history, want)
+	}
+}
+
+func TestCreateOrphanWorktreeSavesStateOnlyOnSuccess(t *testing.T) {
+	repo := newTestRepo(t)
+	t.Chdir(repo)
+	path := filepath.Join(filepath.Dir(repo), "repo.wt", "docs")
+
+	// A worktree registered at the path but missing on disk makes `git
+	// worktree add` fail.
+	runGit(t, repo, "worktree", "add", "--quiet", "-b", "other", path)
+	if err := os.RemoveAll(path); err != nil {
+		t.Fatal(err)
+	}
+	captureOutput(t, func() {
+		if err := CreateOrphanWorktree("docs"); err == nil {
+			t.Fatal("CreateOrphanWorktree succeeded over a registered worktree")
+		}
+	})
+	if history, err := readHistory(); err != nil || len(history) != 0 {
+		t.Errorf("history after a failed create = %q, %v; want it empty", history, err)
+	}
+
+	runGit(t, repo, "worktree", "prune")
+	worktreeList.valid = false
+	captureOutput(t, func() {
+		if err := CreateOrphanWorktree("docs"); err != nil {
+			t.Fatalf("CreateOrphanWorktree: %v", err)
+		}
+	})
+	if history, err := readHistory(); err != nil || !reflect.DeepEqual(history, []string{repo}) {
+		t.Errorf("history after creating = %q, %v; want %q", history, err, []string{repo})
+	}
+}